@@ -9,124 +9,211 @@
 package gosjwt
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
+// authResult 一次认证的结果，status为0表示通过
+type authResult struct {
+	claims   *Claims
+	newToken string // 宽限期内续期签发的新Token，需通过响应头返回
+	status   int
+	message  string
+	err      error          // 失败原因，供gRPC等非HTTP协议映射状态码
+	cookies  []*http.Cookie // Token来自Cookie模式的Cookie时，续期写入的Token与CSRF Cookie
+}
+
+// authFailure 认证失败的结果
+func authFailure(status int, message string) authResult {
+	return authResult{status: status, message: message}
+}
+
+// revokedFailure Token已被撤销
+func revokedFailure() authResult {
+	return authResult{status: http.StatusUnauthorized, message: "Token revoked", err: ErrTokenRevoked}
+}
+
+// storeFailure 存储超时、不可用或请求取消时返回503
+func storeFailure(err error) authResult {
+	res := authResult{status: http.StatusServiceUnavailable, message: "Request canceled", err: err}
+	if errors.Is(err, ErrStoreTimeout) {
+		res.message = "Token store timeout"
+	} else if errors.Is(err, ErrStoreUnavailable) {
+		res.message = "Token store unavailable"
+	}
+	return res
+}
+
+// UserIdContextKey 框架上下文中保存用户ID的键，Gin、Echo使用c.Get读取，Fiber使用c.Locals读取
+const UserIdContextKey = "userID"
+
 // GinMiddleware 创建JWT认证中间件
+// 认证通过后通过c.Get("userID")读取用户ID，请求ctx中同时写入Claims，可使用ClaimsFromContext读取
 func (j *JwtHandler) GinMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		res := j.authenticate(c.Request.Context(), httpTokenRequest{r: c.Request, maxForm: j.maxFormSize()})
+		if res.status != 0 {
+			c.AbortWithStatusJSON(res.status, gin.H{"error": res.message})
 			return
 		}
+		writeRenewal(c.Writer.Header(), j.renewalHeaders(res))
+		c.Request = c.Request.WithContext(withClaims(c.Request.Context(), res.claims))
+		c.Set(UserIdContextKey, res.claims.UserId)
+		c.Next()
+	}
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
-			return
-		}
+// authenticate 认证流程：按Config.TokenLookup提取Token、CSRF校验、撤销检查、解析与宽限期续期，与具体框架无关
+func (j *JwtHandler) authenticate(ctx context.Context, req tokenRequest) authResult {
+	tokenString, from, res := j.extractToken(req)
+	if res.status != 0 {
+		return res
+	}
 
-		if j.isTokenRevoked(tokenString) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token revoked"})
-			return
+	// 浏览器会自动携带任何Cookie，所有来自Cookie的Token都需要CSRF校验，不限于Cookie模式的令牌Cookie
+	if from.Type == LookupCookie {
+		if res = j.checkCSRF(req, tokenString); res.status != 0 {
+			return res
 		}
+	}
 
-		token, claims, err := j.ParseToken(tokenString)
-		if err == nil && token.Valid {
-			c.Set("userID", claims.UserId)
-			c.Next()
-			return
-		}
+	cookie := j.isTokenCookie(from)
 
-		if isExpiredError(err) {
-			j.handleExpiredToken(c, tokenString)
-			return
+	res = j.verifyToken(ctx, tokenString)
+	if res.status == 0 && cookie && res.newToken != "" {
+		// 新Token与绑定它的CSRF Token通过Cookie返回，生成失败时拒绝请求而不是改用其他方式返回
+		cookies, err := j.TokenCookies(res.newToken)
+		if err != nil {
+			return authFailure(http.StatusInternalServerError, "Failed to generate new token")
 		}
+		res.cookies = cookies
+	}
+	return res
+}
 
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+// verifyToken 撤销检查、解析与宽限期续期
+func (j *JwtHandler) verifyToken(ctx context.Context, tokenString string) authResult {
+	revoked, err := j.isTokenRevoked(ctx, tokenString)
+	if err != nil {
+		return storeFailure(err)
+	}
+	if revoked {
+		return revokedFailure()
+	}
+
+	token, claims, err := j.parseUnrevoked(ctx, tokenString)
+	if err == nil && token.Valid {
+		return authResult{claims: claims}
 	}
+
+	if isExpiredError(err) {
+		return j.handleExpiredToken(ctx, tokenString)
+	}
+
+	if isContextError(err) {
+		return storeFailure(err)
+	}
+
+	if errors.Is(err, ErrTokenRevoked) {
+		return revokedFailure()
+	}
+
+	return authFailure(http.StatusUnauthorized, "Invalid token")
 }
 
 // 处理过期Token的宽限期逻辑
-func (j *JwtHandler) handleExpiredToken(c *gin.Context, tokenString string) {
+func (j *JwtHandler) handleExpiredToken(ctx context.Context, tokenString string) authResult {
 	// 1. 解析Token忽略过期错误
 	claims, err := j.parseExpiredToken(tokenString)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid expired token"})
-		return
+		return authFailure(http.StatusUnauthorized, "Invalid expired token")
+	}
+
+	// 全局撤销前签发的过期Token不得续期
+	if j.isIssuedBeforeEpoch(claims) {
+		return revokedFailure()
 	}
 
 	now := time.Now()
-	j.graceMutex.Lock()
-	defer j.graceMutex.Unlock()
+	key := j.TokenKey(tokenString)
 
 	// 2. 检查是否已超过绝对宽限期截止时间
-	if gpToken, exists := j.graceTokens[tokenString]; exists {
-		if now.After(gpToken.deadline) {
+	sctx, cancel := j.storeContext(ctx)
+	gpToken, exists, err := j.store.GetGrace(sctx, key)
+	cancel()
+	if err = storeError(err); isContextError(err) {
+		return storeFailure(err)
+	}
+	if err == nil && exists {
+		if now.After(gpToken.Deadline) {
 			// 宽限期已结束
-			delete(j.graceTokens, tokenString)
-			_ = j.RevokeToken(tokenString)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
-			return
+			sctx, cancel := j.storeContext(ctx)
+			defer cancel()
+			_ = j.store.DeleteGrace(sctx, key)
+			j.scheduler.Cancel(tokenString)
+			_ = j.RevokeTokenContext(ctx, tokenString)
+			return authFailure(http.StatusUnauthorized, "Token expired")
 		}
 
 		// 仍在宽限期内
-		c.Set("userID", claims.UserId)
-		c.Next()
-		return
+		return authResult{claims: claims}
+	}
+
+	// 3. 超过可接受窗口的过期Token不再进入宽限期
+	if now.After(j.acceptDeadline(claims)) {
+		return authFailure(http.StatusUnauthorized, "Token expired")
 	}
 
-	// 3. 首次使用过期Token
-	newToken, err := j.ReleaseToken(claims.UserId)
+	// 4. 首次使用过期Token
+	newToken, err := j.ReleaseTokenContext(ctx, claims.UserId)
+	if isContextError(err) {
+		return storeFailure(err)
+	}
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new token"})
-		return
+		return authFailure(http.StatusInternalServerError, "Failed to generate new token")
 	}
 
-	// 设置绝对截止时间（当前时间+宽限期）
+	// 设置绝对截止时间（当前时间+宽限期），不超过原Token的可接受窗口，避免临近窗口末尾续期时延长旧Token的可用时间
 	deadline := now.Add(time.Duration(j.Config.GracePeriod) * time.Second)
-
-	// 记录到宽限期管理
-	j.graceTokens[tokenString] = &gracePeriodToken{
-		deadline: deadline,
-		newToken: newToken,
+	if accept := j.acceptDeadline(claims); deadline.After(accept) {
+		deadline = accept
 	}
 
-	// 设置响应头返回新Token
-	c.Header("Authorization", "Bearer "+newToken)
-
-	// 异步清理（确保最终会被清理）
-	go func() {
-		time.Sleep(time.Until(deadline) + time.Second) // 稍多等1秒确保
-
-		j.graceMutex.Lock()
-		defer j.graceMutex.Unlock()
-
-		if gpToken, exists := j.graceTokens[tokenString]; exists {
-			if time.Now().After(gpToken.deadline) {
-				delete(j.graceTokens, tokenString)
-				_ = j.RevokeToken(tokenString)
-			}
-		}
-	}()
+	// 记录到宽限期管理，并发请求只有一方写入成功
+	sctx, cancel = j.storeContext(ctx)
+	state, stored, err := j.store.PutGrace(sctx, key, GraceState{
+		Deadline:    deadline,
+		NewTokenKey: j.TokenKey(newToken),
+	}, time.Until(deadline)+graceStateRetention)
+	cancel()
+	if err = storeError(err); isContextError(err) {
+		return storeFailure(err)
+	}
+	if err != nil {
+		return authFailure(http.StatusInternalServerError, "Failed to generate new token")
+	}
 
 	// 允许本次请求通过
-	c.Set("userID", claims.UserId)
-	c.Next()
+	res := authResult{claims: claims}
+	if stored {
+		// 通过响应头返回新Token
+		res.newToken = newToken
+
+		// 交由调度器在截止时间后清理并转入黑名单
+		j.scheduler.Schedule(tokenString, state.Deadline.Add(graceExpirySlack))
+	}
+	return res
 }
 
 // 解析过期Token（忽略过期错误）
 func (j *JwtHandler) parseExpiredToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return j.Config.SigningKey, nil
-	})
+	_, err := j.parseClaims(tokenString, claims)
 	if err != nil {
 		if isExpiredError(err) {
 			return claims, nil // 忽略过期错误
@@ -136,8 +223,36 @@ func (j *JwtHandler) parseExpiredToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// 检查Token是否被撤销
-func (j *JwtHandler) isTokenRevoked(tokenString string) bool {
-	_, exists, err := j.blacklist.Get(tokenString)
-	return err == nil && exists
+// 检查Token是否被撤销，超时与取消作为错误返回，其他存储错误按RevocationPolicy处理
+func (j *JwtHandler) isTokenRevoked(ctx context.Context, tokenString string) (bool, error) {
+	revoked, err := j.isKeyRevoked(ctx, j.TokenKey(tokenString))
+	if err != nil || revoked || !j.Config.LegacyTokenKeys {
+		return revoked, err
+	}
+	// 迁移期间兼容以原始Token为键的历史撤销记录
+	return j.isKeyRevoked(ctx, tokenString)
+}
+
+// isKeyRevoked 启用本地撤销集合时不访问存储
+// 启用布隆过滤器时，仅在过滤器判定可能存在时查询黑名单
+func (j *JwtHandler) isKeyRevoked(ctx context.Context, key string) (bool, error) {
+	if j.revoked != nil {
+		return j.revoked.Contains(key), nil
+	}
+	if j.filter != nil && !j.filter.MayContain(key) {
+		return false, nil
+	}
+	sctx, cancel := j.storeContext(ctx)
+	defer cancel()
+	revoked, err := j.store.IsRevoked(sctx, key)
+	if err = storeError(err); isContextError(err) {
+		return false, err
+	}
+	if err != nil && j.Config.Breaker.RevocationPolicy == RevocationFailClosed {
+		if errors.Is(err, ErrStoreUnavailable) {
+			return false, err
+		}
+		return false, fmt.Errorf("%w: %w", ErrStoreUnavailable, err)
+	}
+	return err == nil && revoked, nil
 }
@@ -0,0 +1,160 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 14:10:31
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 14:10:31
+ * Description: 撤销检查布隆过滤器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultFilterCapacity        = 100000
+	defaultFilterFalsePositive   = 0.01
+	defaultFilterRebuildInterval = 30 * time.Second
+)
+
+// bloomFilter 并发安全的布隆过滤器
+type bloomFilter struct {
+	mu   sync.RWMutex
+	bits []uint64
+	m    uint64 // 位数
+	k    uint64 // 哈希函数个数
+}
+
+// newBloomFilter 按预期容量与误判率计算位数与哈希函数个数
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	if capacity <= 0 {
+		capacity = defaultFilterCapacity
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = defaultFilterFalsePositive
+	}
+
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{bits: make([]uint64, m/64), m: m, k: k}
+}
+
+// locations 双重哈希生成k个位置
+func (f *bloomFilter) locations(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&0xffffffff, sum>>32
+	if h2 == 0 {
+		h2 = 1
+	}
+	return h1, h2
+}
+
+// Add 加入过滤器
+func (f *bloomFilter) Add(key string) {
+	h1, h2 := f.locations(key)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// MayContain 返回false时一定不在集合中，返回true时可能误判
+func (f *bloomFilter) MayContain(key string) bool {
+	h1, h2 := f.locations(key)
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// revocationFilter 撤销检查的概率前置过滤
+// 过滤器判定不存在时直接放行，可能存在时再查询真实黑名单；只保存位图，定期从黑名单全量快照重建
+type revocationFilter struct {
+	mu         sync.Mutex // 串行化写入与重建，读取无锁
+	current    atomic.Pointer[bloomFilter]
+	rebuilding bool     // 正在拉取快照
+	pending    []string // 拉取快照期间新增的撤销，快照中可能缺失
+	capacity   int
+	fpRate     float64
+}
+
+func newRevocationFilter(cfg FilterConfig) *revocationFilter {
+	f := &revocationFilter{
+		capacity: cfg.Capacity,
+		fpRate:   cfg.FalsePositiveRate,
+	}
+	f.current.Store(newBloomFilter(f.capacity, f.fpRate))
+	return f
+}
+
+// Add 写入当前过滤器
+func (f *revocationFilter) Add(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.current.Load().Add(key)
+	if f.rebuilding {
+		f.pending = append(f.pending, key)
+	}
+}
+
+// MayContain 是否可能已撤销
+func (f *revocationFilter) MayContain(key string) bool {
+	return f.current.Load().MayContain(key)
+}
+
+// BeginRebuild 开始拉取快照，此后的新增撤销在重建时补入
+func (f *revocationFilter) BeginRebuild() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebuilding = true
+	f.pending = nil
+}
+
+// AbortRebuild 拉取快照失败，保留当前过滤器
+func (f *revocationFilter) AbortRebuild() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rebuilding = false
+	f.pending = nil
+}
+
+// FinishRebuild 按快照中未过期的记录及拉取期间的新增撤销重建过滤器，剔除已失效的位
+func (f *revocationFilter) FinishRebuild(snapshot map[string]time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	next := newBloomFilter(f.capacity, f.fpRate)
+	for key, expiresAt := range snapshot {
+		if now.Before(expiresAt) {
+			next.Add(key)
+		}
+	}
+	for _, key := range f.pending {
+		next.Add(key)
+	}
+	f.current.Store(next)
+	f.rebuilding = false
+	f.pending = nil
+}
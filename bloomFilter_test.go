@@ -0,0 +1,164 @@
+package gosjwt
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	// 测试用例1: 无漏判且误判率接近预期
+	t.Run("FalsePositiveRate", func(t *testing.T) {
+		f := newBloomFilter(10000, 0.01)
+		for i := 0; i < 10000; i++ {
+			f.Add("revoked-" + strconv.Itoa(i))
+		}
+		for i := 0; i < 10000; i++ {
+			assert.True(t, f.MayContain("revoked-"+strconv.Itoa(i)))
+		}
+
+		falsePositives := 0
+		for i := 0; i < 10000; i++ {
+			if f.MayContain("valid-" + strconv.Itoa(i)) {
+				falsePositives++
+			}
+		}
+		assert.Less(t, falsePositives, 300)
+	})
+
+	// 测试用例2: 按快照重建，剔除过期与快照外的记录，保留拉取快照期间的新增撤销
+	t.Run("RebuildFromSnapshot", func(t *testing.T) {
+		f := newRevocationFilter(FilterConfig{Capacity: 100})
+		f.Add("stale")
+		assert.True(t, f.MayContain("stale"))
+
+		f.BeginRebuild()
+		f.Add("during")
+		f.FinishRebuild(map[string]time.Time{
+			"expired": time.Now().Add(-time.Second),
+			"remote":  time.Now().Add(time.Hour),
+		})
+		assert.False(t, f.MayContain("stale"))
+		assert.False(t, f.MayContain("expired"))
+		assert.True(t, f.MayContain("remote"))
+		assert.True(t, f.MayContain("during"))
+
+		// 拉取失败时保留当前过滤器
+		f.BeginRebuild()
+		f.AbortRebuild()
+		assert.True(t, f.MayContain("remote"))
+	})
+}
+
+func TestRevocationFilter(t *testing.T) {
+	// 测试用例1: 未撤销的Token不查询黑名单
+	t.Run("SkipBlacklistOnMiss", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{
+			Expires:          3600,
+			RevocationFilter: FilterConfig{Enabled: true},
+		})
+
+		counter := &lookupCounter{Store: handler.store}
+		handler.store = counter
+		r := setupGraceRouter(handler)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, performRequest(r, token).Code)
+		assert.Equal(t, 0, counter.lookups)
+
+		// 可能命中时回落到黑名单确认
+		assert.NoError(t, handler.RevokeToken(token))
+		w := performRequest(r, token)
+		assert.Equal(t, 401, w.Code)
+		assert.Contains(t, w.Body.String(), "Token revoked")
+		assert.Equal(t, 1, counter.lookups)
+	})
+
+	// 测试用例2: 其他实例的撤销通过订阅写入过滤器
+	t.Run("RedisPropagation", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		newConfig := func() *Config {
+			config := newRedisTestConfig(mr.Addr())
+			config.RevocationFilter = FilterConfig{Enabled: true}
+			return config
+		}
+
+		a := newTestHandler(t, newConfig())
+
+		// 启动前已存在的撤销记录在初次重建时载入
+		early, err := a.ReleaseToken(2)
+		assert.NoError(t, err)
+		assert.NoError(t, a.RevokeToken(early))
+
+		b := newTestHandler(t, newConfig())
+		assert.True(t, isRevokedForTest(b, early))
+
+		token, err := a.ReleaseToken(3)
+		assert.NoError(t, err)
+		assert.False(t, isRevokedForTest(b, token))
+		assert.NoError(t, a.RevokeToken(token))
+		assert.Eventually(t, func() bool {
+			return isRevokedForTest(b, token)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	// 测试用例3: 断线期间丢失的撤销事件在重新订阅后立即重建过滤器
+	t.Run("ResyncOnReconnect", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.RevocationFilter = FilterConfig{Enabled: true, RebuildInterval: 3600}
+		handler := newTestHandler(t, config)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		key := handler.TokenKey(token)
+		assert.False(t, handler.filter.MayContain(key))
+
+		mr.Close()
+		assert.NoError(t, mr.Set("test_blacklist:"+key, "true"))
+		mr.SetTTL("test_blacklist:"+key, time.Minute)
+		assert.NoError(t, mr.Restart())
+
+		assert.Eventually(t, func() bool {
+			return handler.filter.MayContain(key)
+		}, 10*time.Second, 50*time.Millisecond)
+	})
+}
+
+// 对比黑名单直查与布隆过滤器前置检查（Redis后端，Token均未撤销）
+func BenchmarkRevocationCheck(b *testing.B) {
+	mr := miniredis.RunT(b)
+
+	run := func(b *testing.B, filter bool) {
+		config := newRedisTestConfig(mr.Addr())
+		config.RevocationFilter = FilterConfig{Enabled: filter}
+		handler, err := NewJwtHandler(config)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer handler.Close()
+
+		token, err := handler.ReleaseToken(1)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < 1000; i++ {
+			revoked, _ := handler.ReleaseToken(uint(i + 2))
+			_ = handler.RevokeToken(revoked)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if isRevokedForTest(handler, token) {
+				b.Fatal("未撤销的Token被判定为已撤销")
+			}
+		}
+	}
+
+	b.Run("Blacklist", func(b *testing.B) { run(b, false) })
+	b.Run("BloomFilter", func(b *testing.B) { run(b, true) })
+}
@@ -0,0 +1,406 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 18:20:55
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 18:20:55
+ * Description: 有容量上限的内存存储
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRevocationCapacity 撤销记录已达保留容量上限且均未过期
+var ErrRevocationCapacity = errors.New("撤销记录已达容量上限")
+
+// boundedEntryOverhead 每条记录的估算固定开销(字节)
+const boundedEntryOverhead = 96
+
+const (
+	boundedKindToken   = "token"
+	boundedKindGrace   = "grace"
+	boundedKindSession = "session"
+)
+
+// MemoryStats 有界内存存储的容量与淘汰指标
+type MemoryStats struct {
+	Entries     int    // 可淘汰记录数（Token、宽限期状态与会话）
+	Bytes       int64  // 可淘汰记录的估算占用
+	Revocations int    // 撤销记录数
+	Evicted     uint64 // 因容量不足按LRU淘汰的记录数
+	Expired     uint64 // 到期清理的记录数
+	Rejected    uint64 // 因保留容量已满被拒绝的撤销数
+}
+
+// boundedEntry 可淘汰的记录，同时位于LRU链表与过期时间堆中
+type boundedEntry struct {
+	id     string
+	value  json.RawMessage
+	expiry int64 // Unix毫秒，0表示不过期
+	size   int64
+	elem   *list.Element
+	index  int // 过期时间堆中的位置，-1表示不在堆中
+}
+
+// boundedExpiryQueue 按过期时间排序的最小堆
+type boundedExpiryQueue []*boundedEntry
+
+func (q boundedExpiryQueue) Len() int           { return len(q) }
+func (q boundedExpiryQueue) Less(i, k int) bool { return q[i].expiry < q[k].expiry }
+
+func (q boundedExpiryQueue) Swap(i, k int) {
+	q[i], q[k] = q[k], q[i]
+	q[i].index = i
+	q[k].index = k
+}
+
+func (q *boundedExpiryQueue) Push(x interface{}) {
+	e := x.(*boundedEntry)
+	e.index = len(*q)
+	*q = append(*q, e)
+}
+
+func (q *boundedExpiryQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*q = old[:n-1]
+	return e
+}
+
+// boundedStore 有容量上限的内存存储
+// Token、宽限期状态与会话超出上限时先清理已过期的记录，再按最近最少使用淘汰；
+// 撤销记录单独计数，过期前不会被淘汰，保留容量已满时拒绝新的撤销
+type boundedStore struct {
+	maxEntries    int
+	maxBytes      int64
+	maxRevocation int
+
+	mu          sync.Mutex
+	entries     map[string]*boundedEntry
+	lru         *list.List // 队首为最近使用
+	expiries    boundedExpiryQueue
+	bytes       int64
+	revocations map[string]int64 // key -> 过期时间(Unix毫秒)，0表示不过期
+	epoch       int64
+
+	evicted  uint64
+	expired  uint64
+	rejected uint64
+}
+
+func newBoundedStore(cfg CacheConfig) *boundedStore {
+	return &boundedStore{
+		maxEntries:    cfg.MaxEntries,
+		maxBytes:      cfg.MaxBytes,
+		maxRevocation: cfg.RevocationCapacity,
+		entries:       make(map[string]*boundedEntry),
+		lru:           list.New(),
+		revocations:   make(map[string]int64),
+	}
+}
+
+// newLocalStore 创建进程内存储，配置了容量上限时使用有界存储
+func newLocalStore(cfg CacheConfig) (Store, error) {
+	if cfg.MaxEntries > 0 || cfg.MaxBytes > 0 || cfg.RevocationCapacity > 0 {
+		return newBoundedStore(cfg), nil
+	}
+	return newMemoryStore()
+}
+
+func newBoundedEntry(kind, key string, v interface{}, ttl time.Duration) (*boundedEntry, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("编码存储值失败: %v", err)
+	}
+	id := kind + ":" + key
+	return &boundedEntry{
+		id:     id,
+		value:  value,
+		expiry: expiryMillis(ttl),
+		size:   int64(len(id)+len(value)) + boundedEntryOverhead,
+		index:  -1,
+	}, nil
+}
+
+func (s *boundedStore) set(kind, key string, v interface{}, ttl time.Duration) error {
+	e, err := newBoundedEntry(kind, key, v, ttl)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insert(e)
+	return nil
+}
+
+// insert 写入记录并按需淘汰，调用方需持有锁
+func (s *boundedStore) insert(e *boundedEntry) {
+	if old, ok := s.entries[e.id]; ok {
+		s.remove(old)
+	}
+	e.elem = s.lru.PushFront(e)
+	if e.expiry > 0 {
+		heap.Push(&s.expiries, e)
+	}
+	s.entries[e.id] = e
+	s.bytes += e.size
+	s.evict()
+}
+
+// get 读取未过期的记录并标记为最近使用，调用方需持有锁
+func (s *boundedStore) get(kind, key string, v interface{}) (bool, error) {
+	e, ok := s.entries[kind+":"+key]
+	if !ok {
+		return false, nil
+	}
+	if e.expiry > 0 && e.expiry <= time.Now().UnixMilli() {
+		s.remove(e)
+		s.expired++
+		return false, nil
+	}
+	s.lru.MoveToFront(e.elem)
+	if err := json.Unmarshal(e.value, v); err != nil {
+		return false, fmt.Errorf("解码存储值失败: %v", err)
+	}
+	return true, nil
+}
+
+// lookup 加锁读取记录
+func (s *boundedStore) lookup(kind, key string, v interface{}) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(kind, key, v)
+}
+
+func (s *boundedStore) del(kind, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[kind+":"+key]; ok {
+		s.remove(e)
+	}
+}
+
+func (s *boundedStore) remove(e *boundedEntry) {
+	s.lru.Remove(e.elem)
+	if e.index >= 0 {
+		heap.Remove(&s.expiries, e.index)
+	}
+	delete(s.entries, e.id)
+	s.bytes -= e.size
+}
+
+func (s *boundedStore) overLimit() bool {
+	return (s.maxEntries > 0 && len(s.entries) > s.maxEntries) ||
+		(s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// evict 超出上限时优先清理已过期的记录，其次淘汰最久未使用的记录
+func (s *boundedStore) evict() {
+	now := time.Now().UnixMilli()
+	for s.overLimit() {
+		if len(s.expiries) > 0 && s.expiries[0].expiry <= now {
+			s.remove(s.expiries[0])
+			s.expired++
+			continue
+		}
+		s.remove(s.lru.Back().Value.(*boundedEntry))
+		s.evicted++
+	}
+}
+
+// purgeRevocations 清理已过期的撤销记录，调用方需持有锁
+func (s *boundedStore) purgeRevocations(now int64) {
+	for key, expiry := range s.revocations {
+		if expiry > 0 && expiry <= now {
+			delete(s.revocations, key)
+			s.expired++
+		}
+	}
+}
+
+func (s *boundedStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	return s.set(boundedKindToken, key, rec, ttl)
+}
+
+func (s *boundedStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	var rec TokenRecord
+	found, err := s.lookup(boundedKindToken, key, &rec)
+	return rec, found, err
+}
+
+func (s *boundedStore) DeleteToken(ctx context.Context, key string) error {
+	s.del(boundedKindToken, key)
+	return nil
+}
+
+func (s *boundedStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.revocations[key]; !exists && s.maxRevocation > 0 && len(s.revocations) >= s.maxRevocation {
+		s.purgeRevocations(time.Now().UnixMilli())
+		if len(s.revocations) >= s.maxRevocation {
+			s.rejected++
+			return ErrRevocationCapacity
+		}
+	}
+	s.revocations[key] = expiryMillis(ttl)
+	return nil
+}
+
+func (s *boundedStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.revocations[key]
+	return ok && (expiry == 0 || expiry > time.Now().UnixMilli()), nil
+}
+
+// Revocations 返回未过期的撤销记录，不过期的记录按默认时长保留
+func (s *boundedStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.purgeRevocations(now.UnixMilli())
+	snapshot := make(map[string]time.Time, len(s.revocations))
+	for key, expiry := range s.revocations {
+		if expiry == 0 {
+			snapshot[key] = now.Add(defaultRevocationTTL)
+		} else {
+			snapshot[key] = time.UnixMilli(expiry)
+		}
+	}
+	return snapshot, nil
+}
+
+func (s *boundedStore) GetEpoch(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.epoch, nil
+}
+
+func (s *boundedStore) RaiseEpoch(ctx context.Context, epoch int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if epoch > s.epoch {
+		s.epoch = epoch
+	}
+	return s.epoch, nil
+}
+
+func (s *boundedStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	e, err := newBoundedEntry(boundedKindGrace, key, state, ttl)
+	if err != nil {
+		return GraceState{}, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing GraceState
+	if found, err := s.get(boundedKindGrace, key, &existing); err != nil || found {
+		return existing, false, err
+	}
+	s.insert(e)
+	return state, true, nil
+}
+
+func (s *boundedStore) GetGrace(ctx context.Context, key string) (GraceState, bool, error) {
+	var state GraceState
+	found, err := s.lookup(boundedKindGrace, key, &state)
+	return state, found, err
+}
+
+func (s *boundedStore) DeleteGrace(ctx context.Context, key string) error {
+	s.del(boundedKindGrace, key)
+	return nil
+}
+
+func (s *boundedStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	if sess.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	return s.set(boundedKindSession, sess.ID, sess, ttl)
+}
+
+func (s *boundedStore) GetSession(ctx context.Context, id string) (Session, bool, error) {
+	var sess Session
+	found, err := s.lookup(boundedKindSession, id, &sess)
+	return sess, found, err
+}
+
+func (s *boundedStore) DeleteSession(ctx context.Context, id string) error {
+	s.del(boundedKindSession, id)
+	return nil
+}
+
+// ExportState 返回未过期的撤销记录、宽限期状态与会话，不改变LRU顺序
+func (s *boundedStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	s.purgeRevocations(now)
+	records := make([]StateRecord, 0, len(s.revocations))
+	for key, expiry := range s.revocations {
+		records = append(records, StateRecord{Kind: StateRevocation, Key: key, ExpiresAt: expiry})
+	}
+	for _, e := range s.entries {
+		if e.expiry > 0 && e.expiry <= now {
+			continue
+		}
+		kind, key, _ := strings.Cut(e.id, ":")
+		rec := StateRecord{Kind: kind, ExpiresAt: e.expiry}
+		var target interface{}
+		switch kind {
+		case boundedKindGrace:
+			rec.Key, rec.Grace = key, &GraceState{}
+			target = rec.Grace
+		case boundedKindSession:
+			rec.Session = &Session{}
+			target = rec.Session
+		default:
+			continue
+		}
+		if err := json.Unmarshal(e.value, target); err != nil {
+			return nil, fmt.Errorf("解码存储值失败: %v", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// Stats 返回容量与淘汰指标
+func (s *boundedStore) Stats() MemoryStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return MemoryStats{
+		Entries:     len(s.entries),
+		Bytes:       s.bytes,
+		Revocations: len(s.revocations),
+		Evicted:     s.evicted,
+		Expired:     s.expired,
+		Rejected:    s.rejected,
+	}
+}
+
+func (s *boundedStore) status() StoreStatus {
+	return StoreStatus{Backend: "memory"}
+}
+
+func (s *boundedStore) Close() error {
+	return nil
+}
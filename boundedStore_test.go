@@ -0,0 +1,106 @@
+package gosjwt
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedStore(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 超出条数上限时按LRU淘汰
+	t.Run("LRUEviction", func(t *testing.T) {
+		s := newBoundedStore(CacheConfig{MaxEntries: 3})
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, s.SetToken(ctx, fmt.Sprint("t", i), TokenRecord{UserId: uint(i)}, time.Hour))
+		}
+		// 访问t0使其成为最近使用
+		_, found, _ := s.GetToken(ctx, "t0")
+		assert.True(t, found)
+
+		assert.NoError(t, s.SetToken(ctx, "t3", TokenRecord{UserId: 3}, time.Hour))
+		_, found, _ = s.GetToken(ctx, "t1")
+		assert.False(t, found)
+		_, found, _ = s.GetToken(ctx, "t0")
+		assert.True(t, found)
+
+		stats := s.Stats()
+		assert.Equal(t, 3, stats.Entries)
+		assert.Equal(t, uint64(1), stats.Evicted)
+	})
+
+	// 测试用例2: 优先清理已过期的记录
+	t.Run("ExpiredFirst", func(t *testing.T) {
+		s := newBoundedStore(CacheConfig{MaxEntries: 2})
+		assert.NoError(t, s.SetToken(ctx, "old", TokenRecord{}, time.Hour))
+		assert.NoError(t, s.SetToken(ctx, "short", TokenRecord{}, time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+
+		assert.NoError(t, s.SetToken(ctx, "new", TokenRecord{}, time.Hour))
+		_, found, _ := s.GetToken(ctx, "old")
+		assert.True(t, found)
+
+		stats := s.Stats()
+		assert.Equal(t, uint64(0), stats.Evicted)
+		assert.Equal(t, uint64(1), stats.Expired)
+	})
+
+	// 测试用例3: 按估算字节数限制
+	t.Run("MaxBytes", func(t *testing.T) {
+		s := newBoundedStore(CacheConfig{MaxBytes: 1024})
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, s.SetSession(ctx, Session{ID: fmt.Sprint("sess", i), UserId: uint(i)}, time.Hour))
+		}
+		stats := s.Stats()
+		assert.LessOrEqual(t, stats.Bytes, int64(1024))
+		assert.Greater(t, stats.Evicted, uint64(0))
+	})
+
+	// 测试用例4: 撤销记录不参与淘汰，保留容量已满时拒绝
+	t.Run("RevocationReserved", func(t *testing.T) {
+		s := newBoundedStore(CacheConfig{MaxEntries: 1, RevocationCapacity: 2})
+		assert.NoError(t, s.Revoke(ctx, "r1", time.Hour))
+		assert.NoError(t, s.Revoke(ctx, "r2", time.Millisecond))
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, s.SetToken(ctx, fmt.Sprint("t", i), TokenRecord{}, time.Hour))
+		}
+		revoked, _ := s.IsRevoked(ctx, "r1")
+		assert.True(t, revoked)
+
+		// 已过期的撤销记录腾出容量
+		time.Sleep(5 * time.Millisecond)
+		assert.NoError(t, s.Revoke(ctx, "r3", time.Hour))
+		assert.ErrorIs(t, s.Revoke(ctx, "r4", time.Hour), ErrRevocationCapacity)
+		// 更新已有记录不受容量限制
+		assert.NoError(t, s.Revoke(ctx, "r1", 2*time.Hour))
+
+		stats := s.Stats()
+		assert.Equal(t, 2, stats.Revocations)
+		assert.Equal(t, uint64(1), stats.Rejected)
+	})
+
+	// 测试用例5: 大量过期Token进入宽限期时内存不超过上限
+	t.Run("GraceFlood", func(t *testing.T) {
+		handler, err := NewJwtHandler(&Config{
+			SigningKey:  []byte("bounded-store-key"),
+			Expires:     -1,
+			GracePeriod: 60,
+			Cache:       CacheConfig{Type: "memory", MaxEntries: 50},
+		})
+		assert.NoError(t, err)
+		defer handler.Close()
+		r := setupGraceRouter(handler)
+
+		for i := 0; i < 200; i++ {
+			token, err := handler.ReleaseToken(uint(i))
+			assert.NoError(t, err)
+			assert.Equal(t, 200, performRequest(r, token).Code)
+		}
+		stats := handler.MemoryStats()
+		assert.LessOrEqual(t, stats.Entries, 50)
+		assert.Greater(t, stats.Evicted, uint64(0))
+	})
+}
@@ -0,0 +1,366 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 20:40:18
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 20:40:18
+ * Description: 存储访问的重试与熔断
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrStoreUnavailable 存储不可用，撤销检查按RevocationFailClosed拒绝请求时返回
+var ErrStoreUnavailable = errors.New("存储不可用")
+
+// ErrCircuitOpen 熔断期间存储操作直接失败
+var ErrCircuitOpen = fmt.Errorf("%w: 熔断中", ErrStoreUnavailable)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenDuration     = 5 * time.Second
+	defaultRetryBackoff     = 50 * time.Millisecond
+	defaultMaxBackoff       = time.Second
+)
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常
+	BreakerOpen     BreakerState = "open"      // 熔断中，存储操作直接返回ErrCircuitOpen
+	BreakerHalfOpen BreakerState = "half-open" // 熔断到期，放行一次试探请求
+)
+
+// BreakerEvent 熔断状态变化事件
+type BreakerEvent struct {
+	From     BreakerState
+	To       BreakerState
+	Failures int       // 切换时的连续失败次数
+	Err      error     // 导致熔断的最后一次错误，恢复时为nil
+	At       time.Time // 切换时间
+}
+
+// breakerStore 在存储外层增加重试与熔断
+// 连续失败达到阈值后熔断，到期后放行一次试探请求，成功则恢复，失败则重新计时
+type breakerStore struct {
+	Store
+	cfg          BreakerConfig
+	threshold    int
+	openDuration time.Duration
+	backoff      time.Duration
+	maxBackoff   time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool // 半开状态下已有试探请求在执行
+}
+
+func newBreakerStore(inner Store, cfg BreakerConfig) *breakerStore {
+	s := &breakerStore{
+		Store:        inner,
+		cfg:          cfg,
+		threshold:    defaultFailureThreshold,
+		openDuration: defaultOpenDuration,
+		backoff:      defaultRetryBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		state:        BreakerClosed,
+	}
+	if cfg.FailureThreshold > 0 {
+		s.threshold = cfg.FailureThreshold
+	}
+	if cfg.OpenDuration > 0 {
+		s.openDuration = time.Duration(cfg.OpenDuration) * time.Millisecond
+	}
+	if cfg.RetryBackoff > 0 {
+		s.backoff = time.Duration(cfg.RetryBackoff) * time.Millisecond
+	}
+	if cfg.MaxBackoff > 0 {
+		s.maxBackoff = time.Duration(cfg.MaxBackoff) * time.Millisecond
+	}
+	return s
+}
+
+// unwrap 返回被包装的存储
+func (s *breakerStore) unwrap() Store {
+	return s.Store
+}
+
+// State 当前熔断状态
+func (s *breakerStore) State() BreakerState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == BreakerOpen && time.Since(s.openedAt) >= s.openDuration {
+		return BreakerHalfOpen
+	}
+	return s.state
+}
+
+// do 执行存储操作，失败时按退避重试，熔断期间直接返回ErrCircuitOpen
+// 重试过程中触发熔断时，返回的ErrCircuitOpen包装最后一次操作的错误
+func (s *breakerStore) do(ctx context.Context, op func(ctx context.Context) error) error {
+	delay := s.backoff
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if err := s.allow(); err != nil {
+			if lastErr != nil {
+				return fmt.Errorf("%w: %w", err, lastErr)
+			}
+			return err
+		}
+		err := op(ctx)
+		lastErr = err
+		s.record(err)
+		if err == nil || attempt >= s.cfg.MaxRetries || !isBreakerFailure(err) || ctx.Err() != nil {
+			return err
+		}
+
+		// 在[delay/2, delay]之间随机等待，避免多个实例同时重试
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		if delay *= 2; delay > s.maxBackoff {
+			delay = s.maxBackoff
+		}
+	}
+}
+
+// allow 判断是否放行本次操作
+func (s *breakerStore) allow() error {
+	s.mu.Lock()
+	var ev *BreakerEvent
+	defer func() {
+		s.mu.Unlock()
+		s.emit(ev)
+	}()
+
+	switch s.state {
+	case BreakerClosed:
+		return nil
+	case BreakerOpen:
+		if time.Since(s.openedAt) < s.openDuration {
+			return ErrCircuitOpen
+		}
+		ev = s.transition(BreakerHalfOpen, nil)
+	}
+	// 半开状态只放行一个试探请求
+	if s.probing {
+		return ErrCircuitOpen
+	}
+	s.probing = true
+	return nil
+}
+
+// record 记录操作结果并切换状态
+func (s *breakerStore) record(err error) {
+	s.mu.Lock()
+	var ev *BreakerEvent
+	defer func() {
+		s.mu.Unlock()
+		s.emit(ev)
+	}()
+
+	halfOpen := s.state == BreakerHalfOpen
+	if halfOpen {
+		s.probing = false
+	}
+	if !isBreakerFailure(err) {
+		// 调用方取消不代表存储故障，半开状态下等待下一个试探请求
+		if err == nil {
+			s.failures = 0
+			if halfOpen {
+				ev = s.transition(BreakerClosed, nil)
+			}
+		}
+		return
+	}
+
+	s.failures++
+	if halfOpen || (s.state == BreakerClosed && s.failures >= s.threshold) {
+		s.openedAt = time.Now()
+		ev = s.transition(BreakerOpen, err)
+	}
+}
+
+// transition 切换状态并生成事件，调用方需持有锁
+func (s *breakerStore) transition(to BreakerState, err error) *BreakerEvent {
+	ev := &BreakerEvent{From: s.state, To: to, Failures: s.failures, Err: err, At: time.Now()}
+	s.state = to
+	return ev
+}
+
+// emit 在锁外通知状态变化
+func (s *breakerStore) emit(ev *BreakerEvent) {
+	if ev != nil && s.cfg.OnStateChange != nil {
+		s.cfg.OnStateChange(*ev)
+	}
+}
+
+// isBreakerFailure 计入熔断的错误：调用方取消、熔断本身与容量限制不代表存储故障
+func isBreakerFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, ErrCircuitOpen) &&
+		!errors.Is(err, ErrRevocationCapacity)
+}
+
+func (s *breakerStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	return s.do(ctx, func(ctx context.Context) error {
+		return s.Store.SetToken(ctx, key, rec, ttl)
+	})
+}
+
+func (s *breakerStore) GetToken(ctx context.Context, key string) (rec TokenRecord, found bool, err error) {
+	err = s.do(ctx, func(ctx context.Context) error {
+		rec, found, err = s.Store.GetToken(ctx, key)
+		return err
+	})
+	return rec, found, err
+}
+
+func (s *breakerStore) DeleteToken(ctx context.Context, key string) error {
+	return s.do(ctx, func(ctx context.Context) error {
+		return s.Store.DeleteToken(ctx, key)
+	})
+}
+
+func (s *breakerStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	return s.do(ctx, func(ctx context.Context) error {
+		return s.Store.Revoke(ctx, key, ttl)
+	})
+}
+
+func (s *breakerStore) IsRevoked(ctx context.Context, key string) (revoked bool, err error) {
+	err = s.do(ctx, func(ctx context.Context) error {
+		revoked, err = s.Store.IsRevoked(ctx, key)
+		return err
+	})
+	return revoked, err
+}
+
+func (s *breakerStore) GetEpoch(ctx context.Context) (epoch int64, err error) {
+	err = s.do(ctx, func(ctx context.Context) error {
+		epoch, err = s.Store.GetEpoch(ctx)
+		return err
+	})
+	return epoch, err
+}
+
+func (s *breakerStore) RaiseEpoch(ctx context.Context, epoch int64) (current int64, err error) {
+	err = s.do(ctx, func(ctx context.Context) error {
+		current, err = s.Store.RaiseEpoch(ctx, epoch)
+		return err
+	})
+	return current, err
+}
+
+// PutGrace 重试时上一次写入可能已生效，读到的正是本次写入的状态时视为写入成功
+func (s *breakerStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (actual GraceState, stored bool, err error) {
+	retried := false
+	err = s.do(ctx, func(ctx context.Context) error {
+		if actual, stored, err = s.Store.PutGrace(ctx, key, state, ttl); err == nil && !stored && retried {
+			stored = actual.NewTokenKey == state.NewTokenKey && actual.Deadline.Equal(state.Deadline)
+		}
+		retried = true
+		return err
+	})
+	return actual, stored, err
+}
+
+func (s *breakerStore) GetGrace(ctx context.Context, key string) (state GraceState, found bool, err error) {
+	err = s.do(ctx, func(ctx context.Context) error {
+		state, found, err = s.Store.GetGrace(ctx, key)
+		return err
+	})
+	return state, found, err
+}
+
+func (s *breakerStore) DeleteGrace(ctx context.Context, key string) error {
+	return s.do(ctx, func(ctx context.Context) error {
+		return s.Store.DeleteGrace(ctx, key)
+	})
+}
+
+func (s *breakerStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	return s.do(ctx, func(ctx context.Context) error {
+		return s.Store.SetSession(ctx, sess, ttl)
+	})
+}
+
+func (s *breakerStore) GetSession(ctx context.Context, id string) (sess Session, found bool, err error) {
+	err = s.do(ctx, func(ctx context.Context) error {
+		sess, found, err = s.Store.GetSession(ctx, id)
+		return err
+	})
+	return sess, found, err
+}
+
+func (s *breakerStore) DeleteSession(ctx context.Context, id string) error {
+	return s.do(ctx, func(ctx context.Context) error {
+		return s.Store.DeleteSession(ctx, id)
+	})
+}
+
+func (s *breakerStore) Revocations(ctx context.Context) (snapshot map[string]time.Time, err error) {
+	lister, ok := s.Store.(RevocationLister)
+	if !ok {
+		return nil, nil
+	}
+	err = s.do(ctx, func(ctx context.Context) error {
+		snapshot, err = lister.Revocations(ctx)
+		return err
+	})
+	return snapshot, err
+}
+
+func (s *breakerStore) ExportState(ctx context.Context) (records []StateRecord, err error) {
+	exporter, ok := s.Store.(StateExporter)
+	if !ok {
+		return nil, ErrExportUnsupported
+	}
+	err = s.do(ctx, func(ctx context.Context) error {
+		records, err = exporter.ExportState(ctx)
+		return err
+	})
+	return records, err
+}
+
+func (s *breakerStore) PublishRevocation(ctx context.Context, ev RevocationEvent) error {
+	n, ok := s.Store.(RevocationNotifier)
+	if !ok {
+		return nil
+	}
+	return s.do(ctx, func(ctx context.Context) error {
+		return n.PublishRevocation(ctx, ev)
+	})
+}
+
+func (s *breakerStore) SubscribeRevocations(handle func(ev RevocationEvent)) (func(), error) {
+	if n, ok := s.Store.(RevocationNotifier); ok {
+		return n.SubscribeRevocations(handle)
+	}
+	return func() {}, nil
+}
+
+func (s *breakerStore) status() StoreStatus {
+	status := StoreStatus{Backend: "custom"}
+	if r, ok := s.Store.(statusReporter); ok {
+		status = r.status()
+	}
+	status.Breaker = s.State()
+	return status
+}
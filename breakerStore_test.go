@@ -0,0 +1,226 @@
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyStore 按需让黑名单查询失败，并统计调用次数
+type flakyStore struct {
+	Store
+	failures atomic.Int32 // 剩余失败次数，负数表示一直失败
+	calls    atomic.Int32
+}
+
+var errFlaky = errors.New("connection refused")
+
+func (s *flakyStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.calls.Add(1)
+	if n := s.failures.Load(); n != 0 {
+		if n > 0 {
+			s.failures.Add(-1)
+		}
+		return false, errFlaky
+	}
+	return s.Store.IsRevoked(ctx, key)
+}
+
+// breakerRecorder 记录熔断状态变化
+type breakerRecorder struct {
+	mu     sync.Mutex
+	events []BreakerEvent
+}
+
+func (r *breakerRecorder) record(ev BreakerEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *breakerRecorder) states() []BreakerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	states := make([]BreakerState, 0, len(r.events))
+	for _, ev := range r.events {
+		states = append(states, ev.To)
+	}
+	return states
+}
+
+func newBreakerTestStore(t *testing.T, cfg BreakerConfig) (*breakerStore, *flakyStore) {
+	t.Helper()
+	mem, err := NewStore(CacheConfig{Type: "memory"})
+	assert.NoError(t, err)
+	flaky := &flakyStore{Store: mem}
+	return newBreakerStore(flaky, cfg), flaky
+}
+
+func TestBreakerStore(t *testing.T) {
+	ctx := context.Background()
+
+	// 测试用例1: 连续失败后熔断，到期后试探成功恢复
+	t.Run("OpenAndRecover", func(t *testing.T) {
+		recorder := &breakerRecorder{}
+		s, flaky := newBreakerTestStore(t, BreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 3,
+			OpenDuration:     30,
+			OnStateChange:    recorder.record,
+		})
+		flaky.failures.Store(-1)
+
+		for i := 0; i < 3; i++ {
+			_, err := s.IsRevoked(ctx, "k")
+			assert.ErrorIs(t, err, errFlaky)
+		}
+		assert.Equal(t, BreakerOpen, s.State())
+
+		// 熔断期间不访问存储
+		_, err := s.IsRevoked(ctx, "k")
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.ErrorIs(t, err, ErrStoreUnavailable)
+		assert.Equal(t, int32(3), flaky.calls.Load())
+
+		// 到期后试探失败，重新熔断
+		time.Sleep(40 * time.Millisecond)
+		assert.Equal(t, BreakerHalfOpen, s.State())
+		_, err = s.IsRevoked(ctx, "k")
+		assert.ErrorIs(t, err, errFlaky)
+		assert.Equal(t, BreakerOpen, s.State())
+
+		// 存储恢复后试探成功
+		flaky.failures.Store(0)
+		time.Sleep(40 * time.Millisecond)
+		_, err = s.IsRevoked(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, BreakerClosed, s.State())
+
+		assert.Equal(t, []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}, recorder.states())
+		assert.ErrorIs(t, recorder.events[0].Err, errFlaky)
+		assert.Equal(t, 3, recorder.events[0].Failures)
+	})
+
+	// 测试用例2: 失败后按退避重试
+	t.Run("Retry", func(t *testing.T) {
+		s, flaky := newBreakerTestStore(t, BreakerConfig{Enabled: true, MaxRetries: 2, RetryBackoff: 1})
+		flaky.failures.Store(2)
+		_, err := s.IsRevoked(ctx, "k")
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), flaky.calls.Load())
+
+		flaky.failures.Store(5)
+		_, err = s.IsRevoked(ctx, "k")
+		assert.ErrorIs(t, err, errFlaky)
+		assert.Equal(t, int32(6), flaky.calls.Load())
+	})
+
+	// 测试用例3: 调用方取消不计入失败
+	t.Run("CanceledNotCounted", func(t *testing.T) {
+		s, _ := newBreakerTestStore(t, BreakerConfig{Enabled: true, FailureThreshold: 1})
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		err := s.do(canceled, func(ctx context.Context) error { return ctx.Err() })
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, BreakerClosed, s.State())
+	})
+
+	// 测试用例4: 熔断期间撤销检查按配置放行或拒绝
+	t.Run("RevocationPolicy", func(t *testing.T) {
+		run := func(t *testing.T, policy RevocationPolicy) (*JwtHandler, string) {
+			mem, err := NewStore(CacheConfig{Type: "memory"})
+			assert.NoError(t, err)
+			flaky := &flakyStore{Store: mem}
+			handler, err := NewJwtHandler(&Config{
+				SigningKey: []byte("breaker-test-key"),
+				Expires:    3600,
+				Store:      flaky,
+				Breaker: BreakerConfig{
+					Enabled:          true,
+					FailureThreshold: 1,
+					OpenDuration:     60000,
+					RevocationPolicy: policy,
+				},
+			})
+			assert.NoError(t, err)
+			token, err := handler.ReleaseToken(1)
+			assert.NoError(t, err)
+			flaky.failures.Store(-1)
+			return handler, token
+		}
+
+		handler, token := run(t, "")
+		defer handler.Close()
+		_, claims, err := handler.ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), claims.UserId)
+		assert.Equal(t, BreakerOpen, handler.StoreStatus().Breaker)
+		assert.Equal(t, http.StatusOK, performRequest(setupGraceRouter(handler), token).Code)
+
+		closed, token := run(t, RevocationFailClosed)
+		defer closed.Close()
+		_, _, err = closed.ParseToken(token)
+		assert.ErrorIs(t, err, ErrStoreUnavailable)
+		_, _, err = closed.ParseToken(token)
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		w := performRequest(setupGraceRouter(closed), token)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "Token store unavailable")
+		// 撤销写入失败时返回错误而不是静默忽略
+		assert.ErrorIs(t, closed.RevokeToken(token), ErrCircuitOpen)
+	})
+
+	// 测试用例5: 重试中触发熔断时保留最后一次操作的错误
+	t.Run("TripDuringRetry", func(t *testing.T) {
+		s, flaky := newBreakerTestStore(t, BreakerConfig{Enabled: true, FailureThreshold: 2, MaxRetries: 3, RetryBackoff: 1})
+		flaky.failures.Store(-1)
+		_, err := s.IsRevoked(ctx, "k")
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.ErrorIs(t, err, errFlaky)
+		assert.Equal(t, int32(2), flaky.calls.Load())
+	})
+
+	// 测试用例6: 写入已生效但响应丢失时，重试读到本次写入的状态视为写入成功
+	t.Run("PutGraceRetry", func(t *testing.T) {
+		mem, err := NewStore(CacheConfig{Type: "memory"})
+		assert.NoError(t, err)
+		lost := &lostReplyStore{Store: mem}
+		s := newBreakerStore(lost, BreakerConfig{Enabled: true, MaxRetries: 1, RetryBackoff: 1})
+		defer s.Close()
+
+		state := GraceState{Deadline: time.Now().Add(time.Minute), NewTokenKey: "new-a"}
+		lost.lose.Store(true)
+		actual, stored, err := s.PutGrace(ctx, "old", state, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+		assert.Equal(t, "new-a", actual.NewTokenKey)
+
+		// 已存在其他请求写入的状态时仍返回stored=false
+		lost.lose.Store(true)
+		other := GraceState{Deadline: state.Deadline, NewTokenKey: "new-b"}
+		actual, stored, err = s.PutGrace(ctx, "old", other, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, stored)
+		assert.Equal(t, "new-a", actual.NewTokenKey)
+	})
+}
+
+// lostReplyStore 写入宽限期状态后模拟一次响应丢失
+type lostReplyStore struct {
+	Store
+	lose atomic.Bool
+}
+
+func (s *lostReplyStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	actual, stored, err := s.Store.PutGrace(ctx, key, state, ttl)
+	if err == nil && s.lose.CompareAndSwap(true, false) {
+		return GraceState{}, false, errFlaky
+	}
+	return actual, stored, err
+}
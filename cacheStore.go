@@ -0,0 +1,362 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 15:20:48
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 15:20:48
+ * Description: 基于goscache的内置存储
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zjguoxin/goscache/v2/cache"
+)
+
+const (
+	graceKeyPrefix   = "grace:"
+	sessionKeyPrefix = "session:"
+)
+
+// cacheStore 基于goscache的内存存储
+type cacheStore struct {
+	tokens    cache.CacheInterface // Token元数据
+	blacklist cache.CacheInterface // 撤销记录与全局撤销时间点
+	state     cache.CacheInterface // 宽限期状态与会话
+	mu        sync.Mutex           // 进程内条件写入的互斥
+
+	// goscache无法枚举键，另行记录可导出的撤销记录、宽限期状态与会话
+	index   map[string]int64 // kind:key -> 过期时间(Unix毫秒)，0表示不过期
+	purgeAt int              // 索引达到该条数时清理已过期的项
+}
+
+// cacheIndexMinPurge 索引清理的最小阈值
+const cacheIndexMinPurge = 1024
+
+// newCacheStore 按配置创建存储，Redis连接失败时按失败策略处理
+func newCacheStore(cfg CacheConfig) (Store, error) {
+	switch cfg.Type {
+	case "redis":
+	case "file":
+		return NewFileStore(cfg.FilePath)
+	default:
+		return newLocalStore(cfg)
+	}
+
+	if cfg.FailurePolicy == "" {
+		cfg.FailurePolicy = FailureFallback
+	}
+	switch cfg.FailurePolicy {
+	case FailureFallback, FailureFailClosed, FailureRetry:
+	default:
+		return nil, fmt.Errorf("未知的失败策略: %s", cfg.FailurePolicy)
+	}
+
+	var s Store
+	rs, err := newRedisStore(cfg)
+	switch {
+	case err == nil:
+		s = rs
+	case cfg.FailurePolicy == FailureFailClosed:
+		return nil, fmt.Errorf("Redis连接失败: %v", err)
+	default:
+		fs, err := newFailoverStore(cfg, err)
+		if err != nil {
+			return nil, err
+		}
+		fs.notify()
+		s = fs
+	}
+
+	if cfg.LocalCacheTTL > 0 {
+		return newTieredStore(cfg, s), nil
+	}
+	return s, nil
+}
+
+// newMemoryStore 创建内存存储
+func newMemoryStore() (*cacheStore, error) {
+	caches := make([]cache.CacheInterface, 0, 3)
+	for range [3]struct{}{} {
+		c, err := cache.NewCache(cache.CacheTypeMemory)
+		if err != nil {
+			closeCaches(caches)
+			return nil, fmt.Errorf("内存缓存初始化失败: %v", err)
+		}
+		caches = append(caches, c)
+	}
+	return &cacheStore{
+		tokens:    caches[0],
+		blacklist: caches[1],
+		state:     caches[2],
+		index:     make(map[string]int64),
+		purgeAt:   cacheIndexMinPurge,
+	}, nil
+}
+
+// closeCaches 关闭已创建的缓存
+func closeCaches(caches []cache.CacheInterface) error {
+	var errs []error
+	for _, c := range caches {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// cacheTTL 转换为goscache的过期参数，-1表示不过期
+func cacheTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return -1
+	}
+	return ttl
+}
+
+func (s *cacheStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	return setJSON(s.tokens, key, rec, ttl)
+}
+
+func (s *cacheStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	var rec TokenRecord
+	found, err := getJSON(s.tokens, key, &rec)
+	if err != nil || !found {
+		return TokenRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (s *cacheStore) DeleteToken(ctx context.Context, key string) error {
+	return s.tokens.Delete(key)
+}
+
+func (s *cacheStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.blacklist.Set(key, true, cacheTTL(ttl)); err != nil {
+		return err
+	}
+	s.track(StateRevocation, key, ttl)
+	return nil
+}
+
+func (s *cacheStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	return s.blacklist.Exists(key)
+}
+
+func (s *cacheStore) GetEpoch(ctx context.Context) (int64, error) {
+	val, exists, err := s.blacklist.Get(revocationEpochKey)
+	if err != nil {
+		return 0, fmt.Errorf("读取全局撤销时间点失败: %v", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	return parseEpochValue(val)
+}
+
+func (s *cacheStore) RaiseEpoch(ctx context.Context, epoch int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.GetEpoch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if current >= epoch {
+		return current, nil
+	}
+	if err := s.blacklist.Set(revocationEpochKey, epoch, -1); err != nil {
+		return 0, fmt.Errorf("写入全局撤销时间点失败: %v", err)
+	}
+	return epoch, nil
+}
+
+func (s *cacheStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, found, err := s.GetGrace(ctx, key); err != nil || found {
+		return existing, false, err
+	}
+	if err := setJSON(s.state, graceKeyPrefix+key, state, ttl); err != nil {
+		return GraceState{}, false, err
+	}
+	s.track(StateGrace, key, ttl)
+	return state, true, nil
+}
+
+func (s *cacheStore) GetGrace(ctx context.Context, key string) (GraceState, bool, error) {
+	var state GraceState
+	found, err := getJSON(s.state, graceKeyPrefix+key, &state)
+	return state, found, err
+}
+
+func (s *cacheStore) DeleteGrace(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.index, StateGrace+":"+key)
+	return s.state.Delete(graceKeyPrefix + key)
+}
+
+func (s *cacheStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	if sess.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := setJSON(s.state, sessionKeyPrefix+sess.ID, sess, ttl); err != nil {
+		return err
+	}
+	s.track(StateSession, sess.ID, ttl)
+	return nil
+}
+
+func (s *cacheStore) GetSession(ctx context.Context, id string) (Session, bool, error) {
+	var sess Session
+	found, err := getJSON(s.state, sessionKeyPrefix+id, &sess)
+	return sess, found, err
+}
+
+func (s *cacheStore) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.index, StateSession+":"+id)
+	return s.state.Delete(sessionKeyPrefix + id)
+}
+
+// track 记录可导出的键，索引增长到阈值时清理已过期的项，调用方需持有锁
+func (s *cacheStore) track(kind, key string, ttl time.Duration) {
+	s.index[kind+":"+key] = expiryMillis(ttl)
+	if len(s.index) < s.purgeAt {
+		return
+	}
+	now := time.Now().UnixMilli()
+	for id, expiry := range s.index {
+		if expiry > 0 && expiry <= now {
+			delete(s.index, id)
+		}
+	}
+	s.purgeAt = 2 * len(s.index)
+	if s.purgeAt < cacheIndexMinPurge {
+		s.purgeAt = cacheIndexMinPurge
+	}
+}
+
+// Revocations 按索引返回未过期的撤销记录，不过期的记录按默认时长保留
+func (s *cacheStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	snapshot := make(map[string]time.Time)
+	for id, expiry := range s.index {
+		kind, key, _ := strings.Cut(id, ":")
+		if kind != StateRevocation {
+			continue
+		}
+		switch {
+		case expiry == 0:
+			snapshot[key] = now.Add(defaultRevocationTTL)
+		case expiry > now.UnixMilli():
+			snapshot[key] = time.UnixMilli(expiry)
+		}
+	}
+	return snapshot, nil
+}
+
+// ExportState 按索引读取未过期的撤销记录、宽限期状态与会话
+func (s *cacheStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	records := make([]StateRecord, 0, len(s.index))
+	for id, expiry := range s.index {
+		if expiry > 0 && expiry <= now {
+			delete(s.index, id)
+			continue
+		}
+		kind, key, _ := strings.Cut(id, ":")
+		rec := StateRecord{Kind: kind, Key: key, ExpiresAt: expiry}
+		var (
+			found bool
+			err   error
+		)
+		switch kind {
+		case StateRevocation:
+			found, err = s.blacklist.Exists(key)
+		case StateGrace:
+			rec.Grace = &GraceState{}
+			found, err = getJSON(s.state, graceKeyPrefix+key, rec.Grace)
+		case StateSession:
+			rec.Key = ""
+			rec.Session = &Session{}
+			found, err = getJSON(s.state, sessionKeyPrefix+key, rec.Session)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func (s *cacheStore) status() StoreStatus {
+	return StoreStatus{Backend: "memory"}
+}
+
+func (s *cacheStore) Close() error {
+	return closeCaches([]cache.CacheInterface{s.tokens, s.blacklist, s.state})
+}
+
+// setJSON 以JSON字符串写入，内存与Redis读取结果一致
+// goscache在Redis下会对数值做JSON往返（整数变为float64），在内存下保留原始类型，
+// 统一编码为字符串后按目标结构体解码，避免依赖具体后端的类型
+func setJSON(c cache.CacheInterface, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("编码存储值失败: %v", err)
+	}
+	return c.Set(key, string(data), cacheTTL(ttl))
+}
+
+// getJSON 读取setJSON写入的值，旧版本写入的其他格式视为错误
+func getJSON(c cache.CacheInterface, key string, v interface{}) (bool, error) {
+	val, exists, err := c.Get(key)
+	if err != nil || !exists {
+		return false, err
+	}
+	raw, ok := val.(string)
+	if !ok {
+		return false, fmt.Errorf("无法识别的存储值: %s", key)
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return false, fmt.Errorf("解码存储值失败: %v", err)
+	}
+	return true, nil
+}
+
+// parseEpochValue 兼容内存缓存的原始值与Redis的JSON数值
+func parseEpochValue(val interface{}) (int64, error) {
+	switch v := val.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("无法识别的全局撤销时间点: %v", val)
+	}
+}
@@ -0,0 +1,258 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 23:02:47
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 23:02:47
+ * Description: 服务间调用的客户端凭证：附加Token并自动续期
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultRefreshBefore 默认在到期前多久主动刷新
+const defaultRefreshBefore = 30 * time.Second
+
+// TokenSource 为客户端提供Token，如调用登录接口或由本服务签发
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc 函数形式的TokenSource
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource 始终返回同一Token，适合转发上游请求的Token，到期后依赖服务端的宽限期续期
+func StaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// TokenSource 以指定用户身份签发Token，用于服务间调用
+func (j *JwtHandler) TokenSource(userId uint) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return j.ReleaseTokenContext(ctx, userId)
+	})
+}
+
+// ClientToken 客户端持有的当前Token，HTTP与gRPC凭证共用
+// 首次使用或距到期不足refreshBefore时从source获取新Token；响应中带回续期Token时自动切换，只接受到期时间不早于当前Token的值
+type ClientToken struct {
+	source        TokenSource
+	refreshBefore time.Duration
+
+	mu        sync.RWMutex
+	token     string
+	expiresAt int64 // Unix秒，0表示不过期或未知
+
+	refreshMu sync.Mutex // 同一时间只有一个协程调用source
+}
+
+// NewClientToken 创建客户端Token，refreshBefore<=0时默认30秒；source为空时需先调用SetToken
+func NewClientToken(source TokenSource, refreshBefore time.Duration) *ClientToken {
+	if refreshBefore <= 0 {
+		refreshBefore = defaultRefreshBefore
+	}
+	return &ClientToken{source: source, refreshBefore: refreshBefore}
+}
+
+// Token 返回当前Token，需要时先刷新；刷新失败但仍持有Token时继续使用旧Token，由服务端决定是否接受
+func (c *ClientToken) Token(ctx context.Context) (string, error) {
+	if token, ok := c.current(); ok {
+		return token, nil
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	// 等待期间其他协程可能已完成刷新
+	if token, ok := c.current(); ok {
+		return token, nil
+	}
+
+	if c.source == nil {
+		token, _ := c.current()
+		if token == "" {
+			return "", fmt.Errorf("获取Token失败: 未设置TokenSource")
+		}
+		return token, nil
+	}
+	token, err := c.source.Token(ctx)
+	if err == nil && token != "" {
+		c.SetToken(token)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.token == "" {
+		if err == nil {
+			err = fmt.Errorf("TokenSource返回了空Token")
+		}
+		return "", fmt.Errorf("获取Token失败: %w", err)
+	}
+	return c.token, nil
+}
+
+// current 返回无需刷新的当前Token
+func (c *ClientToken) current() (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.token == "" {
+		return "", false
+	}
+	if c.expiresAt > 0 && time.Until(time.Unix(c.expiresAt, 0)) < c.refreshBefore {
+		return c.token, false
+	}
+	return c.token, true
+}
+
+// SetToken 切换到新Token，到期时间早于当前Token的值被忽略，避免并发响应带回的旧Token覆盖新Token
+func (c *ClientToken) SetToken(token string) {
+	expiresAt := tokenExpiresAt(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && c.expiresAt > 0 && (expiresAt == 0 || expiresAt < c.expiresAt) {
+		return
+	}
+	c.token = token
+	c.expiresAt = expiresAt
+}
+
+// observe 从响应的Authorization中提取续期Token
+func (c *ClientToken) observe(authorization string) {
+	if token := strings.TrimPrefix(authorization, "Bearer "); token != authorization && token != "" {
+		c.SetToken(token)
+	}
+}
+
+// tokenExpiresAt 不校验签名读取到期时间，无法解析时返回0
+func tokenExpiresAt(token string) int64 {
+	claims := &Claims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return 0
+	}
+	return claims.ExpiresAt
+}
+
+// Transport 返回附加Token的http.RoundTripper，base为空时使用http.DefaultTransport
+// 请求已设置Authorization时不覆盖，也不采纳其响应带回的Token；由Transport附加Token的请求在响应头带回续期Token时自动切换
+func (c *ClientToken) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &tokenTransport{tokens: c, base: base}
+}
+
+type tokenTransport struct {
+	tokens *ClientToken
+	base   http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 调用方自带的Token属于其他身份，其响应带回的续期Token不能替换共享的Token
+	if req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	token, err := t.tokens.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	// RoundTripper不得修改原请求
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		t.tokens.observe(resp.Header.Get("Authorization"))
+	}
+	return resp, err
+}
+
+// PerRPCCredentials 返回附加Token的gRPC凭证，requireTLS为false时允许明文连接（仅限内网或测试）
+// 续期Token需配合UnaryClientInterceptor与StreamClientInterceptor从响应header读取
+func (c *ClientToken) PerRPCCredentials(requireTLS bool) credentials.PerRPCCredentials {
+	return &tokenCredentials{tokens: c, requireTLS: requireTLS}
+}
+
+type tokenCredentials struct {
+	tokens     *ClientToken
+	requireTLS bool
+}
+
+func (t *tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := t.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{grpcAuthorizationKey: "Bearer " + token}, nil
+}
+
+func (t *tokenCredentials) RequireTransportSecurity() bool {
+	return t.requireTLS
+}
+
+// UnaryClientInterceptor 从一元调用的响应header读取续期Token
+func (c *ClientToken) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var header metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header))...)
+		c.observeMetadata(header)
+		return err
+	}
+}
+
+// StreamClientInterceptor 从流式调用的响应header读取续期Token
+func (c *ClientToken) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &renewingClientStream{ClientStream: stream, tokens: c}, nil
+	}
+}
+
+func (c *ClientToken) observeMetadata(md metadata.MD) {
+	if values := md.Get(grpcAuthorizationKey); len(values) > 0 {
+		c.observe(values[0])
+	}
+}
+
+// renewingClientStream 收到首条消息或读取header时检查续期Token
+type renewingClientStream struct {
+	grpc.ClientStream
+	tokens *ClientToken
+	once   sync.Once
+}
+
+func (s *renewingClientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	s.tokens.observeMetadata(md)
+	return md, err
+}
+
+func (s *renewingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	// 收到消息或流结束时header已可读取，不会阻塞
+	s.once.Do(func() {
+		if md, herr := s.ClientStream.Header(); herr == nil {
+			s.tokens.observeMetadata(md)
+		}
+	})
+	return err
+}
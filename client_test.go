@@ -0,0 +1,235 @@
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestClientToken(t *testing.T) {
+	// 服务端返回本次请求携带的Authorization
+	echoAuthorization := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}
+	get := func(t *testing.T, client *http.Client, url string) (string, http.Header) {
+		resp, err := client.Get(url)
+		if !assert.NoError(t, err) {
+			return "", nil
+		}
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp.Header
+	}
+
+	// 测试用例1: Transport附加Token，宽限期续期后自动切换到新Token
+	t.Run("TransportRenewal", func(t *testing.T) {
+		issuer := newTestHandler(t, &Config{Expires: -1, GracePeriod: 60})
+		server := newTestHandler(t, &Config{Expires: 3600, GracePeriod: 60})
+		expired, err := issuer.ReleaseToken(7)
+		assert.NoError(t, err)
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", echoAuthorization)
+		ts := httptest.NewServer(server.HttpMiddleware()(mux))
+		defer ts.Close()
+
+		tokens := NewClientToken(StaticTokenSource(expired), 0)
+		client := &http.Client{Transport: tokens.Transport(nil)}
+
+		sent, header := get(t, client, ts.URL)
+		assert.Equal(t, "Bearer "+expired, sent)
+		renewed := header.Get("Authorization")
+		assert.Contains(t, renewed, "Bearer ")
+
+		// 后续请求使用续期Token
+		sent, header = get(t, client, ts.URL)
+		assert.Equal(t, renewed, sent)
+		assert.Empty(t, header.Get("Authorization"))
+		token, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, renewed, "Bearer "+token)
+	})
+
+	// 测试用例2: 请求已设置Authorization时不覆盖
+	t.Run("ExplicitHeader", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(echoAuthorization))
+		defer ts.Close()
+
+		var calls int32
+		tokens := NewClientToken(TokenSourceFunc(func(ctx context.Context) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return "unused", nil
+		}), 0)
+		client := &http.Client{Transport: tokens.Transport(nil)}
+
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer explicit")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "Bearer explicit", string(body))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	})
+
+	// 测试用例3: 距到期不足refreshBefore时主动刷新
+	t.Run("ProactiveRefresh", func(t *testing.T) {
+		short := newTestHandler(t, &Config{Expires: 10, GracePeriod: 60})
+		long := newTestHandler(t, &Config{Expires: 3600, GracePeriod: 60})
+
+		var calls int32
+		source := TokenSourceFunc(func(ctx context.Context) (string, error) {
+			atomic.AddInt32(&calls, 1)
+			return short.ReleaseTokenContext(ctx, 7)
+		})
+
+		// 10秒有效期大于refreshBefore，缓存复用
+		tokens := NewClientToken(source, time.Second)
+		first, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		second, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+		// 10秒有效期小于refreshBefore，每次都刷新
+		tokens = NewClientToken(source, time.Minute)
+		_, err = tokens.Token(context.Background())
+		assert.NoError(t, err)
+		_, err = tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+		// 通过JwtHandler.TokenSource签发
+		tokens = NewClientToken(long.TokenSource(9), time.Minute)
+		token, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		_, claims, err := long.ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(9), claims.UserId)
+	})
+
+	// 测试用例4: 刷新失败时继续使用旧Token，无Token时返回错误
+	t.Run("RefreshFailure", func(t *testing.T) {
+		issuer := newTestHandler(t, &Config{Expires: -1, GracePeriod: 60})
+		expired, err := issuer.ReleaseToken(7)
+		assert.NoError(t, err)
+
+		failing := TokenSourceFunc(func(ctx context.Context) (string, error) {
+			return "", errors.New("login failed")
+		})
+		tokens := NewClientToken(failing, 0)
+		_, err = tokens.Token(context.Background())
+		assert.Error(t, err)
+
+		tokens.SetToken(expired)
+		token, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, expired, token)
+
+		_, err = NewClientToken(nil, 0).Token(context.Background())
+		assert.Error(t, err)
+	})
+
+	// 测试用例5: 只接受到期时间不早于当前Token的新Token
+	t.Run("SetTokenNewerOnly", func(t *testing.T) {
+		older, err := newTestHandler(t, &Config{Expires: 60, GracePeriod: 60}).ReleaseToken(7)
+		assert.NoError(t, err)
+		newer, err := newTestHandler(t, &Config{Expires: 3600, GracePeriod: 60}).ReleaseToken(7)
+		assert.NoError(t, err)
+
+		tokens := NewClientToken(nil, 0)
+		tokens.SetToken(newer)
+		tokens.SetToken(older)
+		tokens.SetToken("not-a-jwt")
+		token, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, newer, token)
+	})
+
+	// 测试用例6: gRPC凭证附加Token，一元与流式调用均自动切换到续期Token
+	t.Run("GrpcRenewal", func(t *testing.T) {
+		req := &grpc_health_v1.HealthCheckRequest{}
+		for _, streaming := range []bool{false, true} {
+			issuer := newTestHandler(t, &Config{Expires: -1, GracePeriod: 60})
+			server := newTestHandler(t, &Config{Expires: 3600, GracePeriod: 60})
+			expired, err := issuer.ReleaseToken(8)
+			assert.NoError(t, err)
+
+			tokens := NewClientToken(StaticTokenSource(expired), 0)
+			client := dialGrpcTest(t, startGrpcTestServer(t, server),
+				grpc.WithPerRPCCredentials(tokens.PerRPCCredentials(false)),
+				grpc.WithUnaryInterceptor(tokens.UnaryClientInterceptor()),
+				grpc.WithStreamInterceptor(tokens.StreamClientInterceptor()),
+			)
+
+			var header metadata.MD
+			if streaming {
+				stream, err := client.Watch(context.Background(), req)
+				assert.NoError(t, err)
+				_, err = stream.Recv()
+				assert.NoError(t, err)
+				header, err = stream.Header()
+				assert.NoError(t, err)
+			} else {
+				_, err = client.Check(context.Background(), req, grpc.Header(&header))
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, []string{"8"}, header.Get("user-id"))
+			renewed := header.Get("authorization")
+			if !assert.Len(t, renewed, 1) {
+				continue
+			}
+
+			token, err := tokens.Token(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, renewed[0], "Bearer "+token, "streaming=%v", streaming)
+
+			// 续期Token可直接通过认证，不再触发宽限期
+			header = nil
+			_, err = client.Check(context.Background(), req, grpc.Header(&header))
+			assert.NoError(t, err)
+			assert.Empty(t, header.Get("authorization"))
+		}
+	})
+
+	// 测试用例7: 调用方自带Authorization时不采纳响应带回的续期Token
+	t.Run("ExplicitHeaderIgnoresRenewal", func(t *testing.T) {
+		h := newTestHandler(t, &Config{Expires: 3600, GracePeriod: 60})
+		shared, err := h.ReleaseToken(7)
+		assert.NoError(t, err)
+		foreign := newTestHandler(t, &Config{Expires: 365 * 24 * 3600})
+		other, err := foreign.ReleaseToken(8)
+		assert.NoError(t, err)
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Authorization", "Bearer "+other)
+		}))
+		defer ts.Close()
+
+		tokens := NewClientToken(StaticTokenSource(shared), 0)
+		client := &http.Client{Transport: tokens.Transport(nil)}
+		req, err := http.NewRequest("GET", ts.URL, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer explicit")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+
+		token, err := tokens.Token(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, shared, token)
+	})
+}
@@ -0,0 +1,200 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 11:48:26
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 11:48:26
+ * Description: 命令行工具
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	gosjwt "github.com/zjguoxin/gos-jwt"
+)
+
+const usage = `用法: gosjwt <命令> [参数]
+
+命令:
+  revoke-all   撤销指定时间之前签发的全部Token
+  migrate-keys 将以原始Token为键的历史记录迁移为HMAC键
+  export       以JSON Lines格式导出撤销记录、宽限期状态与会话
+  import       导入export的输出，用于备份恢复与迁移
+
+执行 gosjwt <命令> -h 查看命令参数`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "revoke-all":
+		err = revokeAll(os.Args[2:])
+	case "migrate-keys":
+		err = migrateKeys(os.Args[2:])
+	case "export":
+		err = exportState(os.Args[2:])
+	case "import":
+		err = importState(os.Args[2:])
+	case "-h", "--help", "help":
+		fmt.Println(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		os.Exit(1)
+	}
+}
+
+// cacheFlags 注册共享缓存连接参数，连接失败时直接报错而不回退到内存
+// fileSupported为false的命令只能操作Redis，不注册-type与-file
+func cacheFlags(fs *flag.FlagSet, fileSupported bool) *gosjwt.CacheConfig {
+	cfg := &gosjwt.CacheConfig{Type: "redis", FailurePolicy: gosjwt.FailureFailClosed}
+	if fileSupported {
+		fs.StringVar(&cfg.Type, "type", "redis", "存储类型：redis 或 file")
+		fs.StringVar(&cfg.FilePath, "file", "", "Type为file时的数据文件路径")
+	}
+	fs.StringVar(&cfg.RedisAddr, "redis-addr", "127.0.0.1:6379", "Redis地址")
+	fs.StringVar(&cfg.RedisPass, "redis-pass", "", "Redis密码")
+	fs.IntVar(&cfg.RedisDB, "redis-db", 0, "Redis数据库")
+	fs.StringVar(&cfg.Prefix, "prefix", "", "缓存前缀，需与服务配置一致")
+
+	// 部署模式、ACL与TLS
+	fs.Func("redis-addrs", "Sentinel或Cluster节点地址，逗号分隔，可重复指定；设置后忽略-redis-addr", func(v string) error {
+		for _, addr := range strings.Split(v, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				cfg.RedisAddrs = append(cfg.RedisAddrs, addr)
+			}
+		}
+		return nil
+	})
+	fs.StringVar(&cfg.RedisMasterName, "redis-master", "", "Sentinel监控的主节点名称，设置后使用Sentinel模式")
+	fs.BoolVar(&cfg.RedisCluster, "redis-cluster", false, "使用Redis Cluster")
+	fs.StringVar(&cfg.RedisUsername, "redis-user", "", "Redis ACL用户名")
+	fs.StringVar(&cfg.SentinelUsername, "sentinel-user", "", "Sentinel节点的ACL用户名")
+	fs.StringVar(&cfg.SentinelPassword, "sentinel-pass", "", "Sentinel节点的密码")
+	fs.BoolVar(&cfg.RedisTLS.Enabled, "tls", false, "使用TLS连接Redis")
+	fs.StringVar(&cfg.RedisTLS.CAFile, "tls-ca", "", "自定义CA证书(PEM)，为空时使用系统证书")
+	fs.StringVar(&cfg.RedisTLS.CertFile, "tls-cert", "", "客户端证书(PEM)，与-tls-key同时设置时启用双向认证")
+	fs.StringVar(&cfg.RedisTLS.KeyFile, "tls-key", "", "客户端私钥(PEM)")
+	fs.StringVar(&cfg.RedisTLS.ServerName, "tls-server-name", "", "校验的服务端名称，默认取连接地址的主机名")
+	fs.BoolVar(&cfg.RedisTLS.InsecureSkipVerify, "tls-insecure", false, "跳过服务端证书校验，仅用于测试")
+	return cfg
+}
+
+func revokeAll(args []string) error {
+	fs := flag.NewFlagSet("revoke-all", flag.ExitOnError)
+	cacheCfg := cacheFlags(fs, false)
+	before := fs.String("before", "", "撤销该时间之前签发的Token，支持RFC3339或Unix秒，不能晚于当前时间一分钟以上，默认当前时间")
+	_ = fs.Parse(args)
+
+	t := time.Now()
+	if *before != "" {
+		parsed, err := gosjwt.ParseEpochTime(*before)
+		if err != nil {
+			return fmt.Errorf("无法解析时间: %s", *before)
+		}
+		t = parsed
+	}
+
+	epoch, err := gosjwt.RaiseRevocationEpoch(&gosjwt.Config{Cache: *cacheCfg}, t)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("全局撤销时间点: %s (%d)\n", epoch.Format(time.RFC3339), epoch.Unix())
+	return nil
+}
+
+func migrateKeys(args []string) error {
+	fs := flag.NewFlagSet("migrate-keys", flag.ExitOnError)
+	cacheCfg := cacheFlags(fs, false)
+	signingKey := fs.String("signing-key", os.Getenv("GOSJWT_SIGNING_KEY"), "签名密钥，默认读取环境变量 GOSJWT_SIGNING_KEY")
+	keySecret := fs.String("key-secret", os.Getenv("GOSJWT_TOKEN_KEY_SECRET"), "存储键HMAC密钥，需与服务的 TokenKeySecret 一致，默认读取环境变量 GOSJWT_TOKEN_KEY_SECRET")
+	_ = fs.Parse(args)
+
+	if *signingKey == "" && *keySecret == "" {
+		return fmt.Errorf("需要指定 -signing-key 或 -key-secret")
+	}
+
+	migrated, err := gosjwt.MigrateTokenKeys(&gosjwt.Config{
+		SigningKey:     []byte(*signingKey),
+		TokenKeySecret: []byte(*keySecret),
+		Cache:          *cacheCfg,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已迁移记录: %d\n", migrated)
+	return nil
+}
+
+func exportState(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	cacheCfg := cacheFlags(fs, true)
+	out := fs.String("out", "-", "输出文件，- 表示标准输出")
+	_ = fs.Parse(args)
+
+	handler, err := gosjwt.NewJwtHandler(&gosjwt.Config{Cache: *cacheCfg})
+	if err != nil {
+		return err
+	}
+	defer handler.Close()
+
+	w := os.Stdout
+	if *out != "-" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+	}
+	exported, err := handler.ExportState(context.Background(), w)
+	if w != os.Stdout {
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "已导出记录: %d\n", exported)
+	return nil
+}
+
+func importState(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	cacheCfg := cacheFlags(fs, true)
+	in := fs.String("in", "-", "输入文件，- 表示标准输入")
+	_ = fs.Parse(args)
+
+	r := os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	handler, err := gosjwt.NewJwtHandler(&gosjwt.Config{Cache: *cacheCfg})
+	if err != nil {
+		return err
+	}
+	defer handler.Close()
+
+	imported, err := handler.ImportState(context.Background(), r)
+	fmt.Fprintf(os.Stderr, "已导入记录: %d\n", imported)
+	return err
+}
@@ -9,6 +9,10 @@
 package gosjwt
 
 import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
 	"github.com/dgrijalva/jwt-go"
 )
 
@@ -17,19 +21,123 @@ type Claims struct {
 	jwt.StandardClaims
 }
 
+// FailurePolicy Redis启动时不可用的处理策略
+type FailurePolicy string
+
+const (
+	FailureFallback   FailurePolicy = "fallback" // 回退到内存缓存并报告降级状态（默认）
+	FailureFailClosed FailurePolicy = "fail"     // 直接返回错误，处理器创建失败
+	FailureRetry      FailurePolicy = "retry"    // 先回退到内存缓存，后台重连成功后切回Redis
+)
+
 type CacheConfig struct {
-	Type      string // "memory" 或 "redis"
+	Type      string // "memory"、"redis" 或 "file"
 	RedisAddr string // Redis地址，如 "localhost:6379"
 	RedisPass string // Redis密码
-	RedisDB   int    // Redis数据库
+	RedisDB   int    // Redis数据库，Cluster模式下只能为0
 	Prefix    string // 缓存前缀
+	FilePath  string // Type为"file"时的数据文件路径，撤销记录与会话重启后仍然有效
+
+	// Redis部署模式、认证与连接池
+	RedisAddrs        []string  // Sentinel或Cluster节点地址，设置后忽略RedisAddr
+	RedisMasterName   string    // Sentinel监控的主节点名称，设置后使用Sentinel模式
+	RedisCluster      bool      // 使用Redis Cluster，RedisAddrs只有一个地址（如云服务配置端点）时也按Cluster连接
+	RedisUsername     string    // ACL用户名，为空时使用default用户
+	SentinelUsername  string    // Sentinel节点的ACL用户名
+	SentinelPassword  string    // Sentinel节点的密码
+	RedisTLS          TLSConfig // TLS连接配置
+	RedisPoolSize     int       // 每个节点的连接池大小，默认100
+	RedisMinIdleConns int       // 每个节点的最小空闲连接数，默认20
+
+	FailurePolicy FailurePolicy            // Redis不可用时的处理策略，默认回退到内存缓存
+	RetryInterval int                      // FailureRetry策略下的重连间隔(秒)，默认5秒
+	OnFailover    func(status StoreStatus) // 回退到内存缓存与切回Redis时的回调，在触发切换的协程中同步执行，不应阻塞
+
+	// 内存存储容量上限，任一项大于0时使用有界存储
+	MaxEntries         int   // Token、宽限期状态与会话的最大条数，超出后按LRU淘汰
+	MaxBytes           int64 // 上述记录的最大估算占用(字节)
+	RevocationCapacity int   // 撤销记录的保留容量，过期前不会被淘汰，已满时撤销返回ErrRevocationCapacity，0表示不限制
+
+	// Redis存储的本地一级缓存，LocalCacheTTL大于0时启用
+	LocalCacheTTL  int // Token元数据与撤销检查结果在本地缓存的时长(毫秒)，撤销时通过广播立即清除各实例的缓存
+	LocalCacheSize int // 本地缓存最大条数，默认10000
+}
+
+// TLSConfig Redis TLS连接配置
+type TLSConfig struct {
+	Enabled            bool        // 是否启用TLS
+	CAFile             string      // 自定义CA证书(PEM)，为空时使用系统证书
+	CertFile           string      // 客户端证书(PEM)，与KeyFile同时设置时启用双向认证
+	KeyFile            string      // 客户端私钥(PEM)
+	ServerName         string      // 校验的服务端名称，默认取连接地址的主机名
+	InsecureSkipVerify bool        // 跳过服务端证书校验，仅用于测试
+	Config             *tls.Config // 完整的TLS配置，设置后忽略以上文件配置
+}
+
+// RevocationPolicy 存储故障时撤销检查的处理方式
+type RevocationPolicy string
+
+const (
+	RevocationFailOpen   RevocationPolicy = "open"   // 视为未撤销并放行（默认），可用性优先
+	RevocationFailClosed RevocationPolicy = "closed" // 拒绝请求并返回503，安全优先
+)
+
+// BreakerConfig 存储访问的重试与熔断配置
+type BreakerConfig struct {
+	Enabled          bool                  // 是否启用
+	FailureThreshold int                   // 连续失败多少次后熔断，默认5
+	OpenDuration     int                   // 熔断持续时间(毫秒)，之后放行一次试探请求，默认5000
+	MaxRetries       int                   // 单次操作失败后的重试次数，默认0
+	RetryBackoff     int                   // 首次重试前的等待(毫秒)，之后指数增长，默认50
+	MaxBackoff       int                   // 重试等待上限(毫秒)，默认1000
+	RevocationPolicy RevocationPolicy      // 撤销检查遇到存储错误（含熔断期间与重试后仍失败）时的处理方式，未启用熔断时同样生效，默认RevocationFailOpen
+	OnStateChange    func(ev BreakerEvent) // 熔断状态变化回调，在触发切换的协程中同步执行，不应阻塞
+}
+
+// FilterConfig 撤销检查布隆过滤器配置
+// 过滤器判定不存在时直接放行：其他实例的撤销广播丢失时，该Token在本实例最长于一个重建间隔内仍可通过
+type FilterConfig struct {
+	Enabled           bool    // 是否启用
+	Capacity          int     // 预期撤销记录数，默认100000
+	FalsePositiveRate float64 // 期望误判率，默认0.01
+	RebuildInterval   int     // 从黑名单重建的间隔(秒)，默认30秒，即广播丢失时撤销生效的最大延迟；订阅断线重连后立即重建
+}
+
+// CookieConfig 浏览器Cookie模式配置，启用后从Cookie读取Token，宽限期续期时更新Cookie
+type CookieConfig struct {
+	Enabled     bool          // 是否启用
+	Name        string        // Token Cookie名称，默认"gosjwt_token"
+	Domain      string        // Cookie域名，默认为当前主机
+	Path        string        // Cookie路径，默认"/"
+	SameSite    http.SameSite // 默认http.SameSiteLaxMode
+	Insecure    bool          // 不设置Secure属性，仅用于本地HTTP开发
+	CSRFCookie  string        // 前端可读的CSRF Cookie名称，默认"gosjwt_csrf"
+	CSRFHeader  string        // 提交CSRF Token的请求头，默认"X-CSRF-Token"
+	DisableCSRF bool          // 关闭CSRF校验，仅在SameSite=Strict等已有防护时使用
 }
 
 type Config struct {
 	SigningKey             []byte
 	Issuer                 string
-	Expires                int         // 过期时间(小时)
-	Cache                  CacheConfig // 缓存配置
-	GracePeriod            int         // 宽限期(秒)
-	BlacklistCleanDuration int         // 宽限期/黑名单清理间隔(分钟)
+	Expires                int           // 过期时间(小时)
+	Cache                  CacheConfig   // 缓存配置
+	Store                  Store         // 自定义存储，为空时按Cache配置创建内置存储；处理器关闭时一并关闭
+	TokenKeySecret         []byte        // 计算存储键的HMAC密钥，默认由SigningKey派生
+	LegacyTokenKeys        bool          // 迁移期间兼容以原始Token为键的历史撤销记录
+	StoreTimeout           int           // 单次存储操作的超时(毫秒)，与调用方ctx取较早者，0表示不限制
+	GracePeriod            int           // 宽限期(秒)
+	BlacklistCleanDuration int           // 已废弃：宽限期到期改由调度器按截止时间处理
+	Leeway                 int           // 允许的时钟偏差(秒)，校验exp、nbf与iat时放宽该时长
+	EpochSyncInterval      int           // Redis缓存下全局撤销时间点的同步间隔(秒)，默认1秒
+	LocalRevocation        bool          // 启用本地撤销集合，鉴权时不再访问黑名单缓存；其他实例的撤销广播丢失时，该Token在本实例最长于一个校准间隔内仍可通过
+	RevocationSyncInterval int           // 本地撤销集合全量校准间隔(秒)，默认10秒；订阅断线重连后立即校准
+	RevocationFilter       FilterConfig  // 撤销检查布隆过滤器
+	Breaker                BreakerConfig // 存储访问的重试与熔断
+	TokenLookup            []TokenLookup // 按顺序查找Token的来源，默认为Authorization头部的Bearer Token
+	MaxTokenSize           int           // Token最大长度(字节)，超出时直接拒绝，默认8192；表单来源的请求体上限为该值加4KB
+	Cookie                 CookieConfig  // 浏览器Cookie模式
+
+	// 撤销记录保留策略，默认保留至 过期时间+宽限期+时钟偏差
+	RevocationDefaultTTL int                                // Token无过期时间时的保留时长(秒)，默认24小时
+	RevocationTTLFunc    func(claims *Claims) time.Duration // 自定义保留时长，返回值<=0时使用默认策略
 }
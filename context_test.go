@@ -0,0 +1,113 @@
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowStore 黑名单查询阻塞到ctx结束，模拟响应缓慢的Redis
+type slowStore struct {
+	Store
+	err error // 不为nil时立即返回该错误
+}
+
+func (s *slowStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+	<-ctx.Done()
+	return false, ctx.Err()
+}
+
+func newSlowHandler(t *testing.T, storeTimeout int, err error) *JwtHandler {
+	t.Helper()
+	store, e := NewStore(CacheConfig{Type: "memory"})
+	assert.NoError(t, e)
+	return newTestHandler(t, &Config{
+		Expires:      3600,
+		StoreTimeout: storeTimeout,
+		Store:        &slowStore{Store: store, err: err},
+	})
+}
+
+func TestContext(t *testing.T) {
+	// 测试用例1: 超过StoreTimeout返回ErrStoreTimeout
+	t.Run("StoreTimeout", func(t *testing.T) {
+		handler := newSlowHandler(t, 20, nil)
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+
+		start := time.Now()
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrStoreTimeout)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, errors.Is(err, ErrTokenRevoked))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	// 测试用例2: 调用方的截止时间同样生效
+	t.Run("CallerDeadline", func(t *testing.T) {
+		handler := newSlowHandler(t, 0, nil)
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, _, err = handler.ParseTokenContext(ctx, token)
+		assert.ErrorIs(t, err, ErrStoreTimeout)
+
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		_, _, err = handler.ParseTokenContext(ctx, token)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.False(t, errors.Is(err, ErrStoreTimeout))
+	})
+
+	// 测试用例3: 其他存储错误沿用原有的放行行为
+	t.Run("OtherErrors", func(t *testing.T) {
+		handler := newSlowHandler(t, 20, errors.New("boom"))
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		_, claims, err := handler.ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), claims.UserId)
+	})
+
+	// 测试用例4: 中间件使用请求的ctx，超时返回503
+	t.Run("Middleware", func(t *testing.T) {
+		handler := newSlowHandler(t, 20, nil)
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+
+		w := performRequest(setupGraceRouter(handler), token)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "Token store timeout")
+	})
+
+	// 测试用例5: Redis存储将ctx传递到每个命令
+	t.Run("Redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		handler := newTestHandler(t, newRedisTestConfig(mr.Addr()))
+
+		expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		_, err := handler.ReleaseTokenContext(expired, 1)
+		assert.ErrorIs(t, err, ErrStoreTimeout)
+
+		token, err := handler.ReleaseTokenContext(context.Background(), 1)
+		assert.NoError(t, err)
+		_, _, err = handler.ParseTokenContext(expired, token)
+		assert.ErrorIs(t, err, ErrStoreTimeout)
+		assert.ErrorIs(t, handler.RevokeTokenContext(expired, token), ErrStoreTimeout)
+
+		assert.NoError(t, handler.RevokeTokenContext(context.Background(), token))
+		_, _, err = handler.ParseTokenContext(context.Background(), token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+	})
+}
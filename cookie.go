@@ -0,0 +1,202 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/20 00:48:09
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/20 00:48:09
+ * Description: 浏览器Cookie模式与CSRF防护
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	defaultCookieName     = "gosjwt_token"
+	defaultCSRFCookieName = "gosjwt_csrf"
+	defaultCSRFHeaderName = "X-CSRF-Token"
+	csrfNonceSize         = 16
+)
+
+func (c CookieConfig) name() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return defaultCookieName
+}
+
+func (c CookieConfig) csrfCookieName() string {
+	if c.CSRFCookie != "" {
+		return c.CSRFCookie
+	}
+	return defaultCSRFCookieName
+}
+
+func (c CookieConfig) csrfHeaderName() string {
+	if c.CSRFHeader != "" {
+		return c.CSRFHeader
+	}
+	return defaultCSRFHeaderName
+}
+
+// newCookie 按配置填充Cookie的公共属性
+func (c CookieConfig) newCookie(name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	sameSite := c.SameSite
+	if sameSite == 0 {
+		sameSite = http.SameSiteLaxMode
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		MaxAge:   maxAge,
+		Secure:   !c.Insecure,
+		HttpOnly: httpOnly,
+		SameSite: sameSite,
+	}
+}
+
+// TokenCookies 生成携带Token的HttpOnly Cookie与前端可读的CSRF Cookie，用于登录响应
+// Cookie有效期覆盖Token过期后的宽限期，以便浏览器在宽限期内仍携带旧Token完成续期
+// CSRF Token绑定该Token，宽限期续期时随新Token一起更新
+func (j *JwtHandler) TokenCookies(token string) ([]*http.Cookie, error) {
+	claims := &Claims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return nil, fmt.Errorf("解析Token失败: %w", err)
+	}
+
+	maxAge := 0 // 无过期时间的Token使用会话Cookie
+	if claims.ExpiresAt > 0 {
+		maxAge = int(time.Until(j.acceptDeadline(claims)) / time.Second)
+		if maxAge <= 0 {
+			maxAge = -1
+		}
+	}
+
+	cfg := j.Config.Cookie
+	cookies := []*http.Cookie{cfg.newCookie(cfg.name(), token, maxAge, true)}
+	if !cfg.DisableCSRF {
+		csrf, err := j.newCSRFToken(token)
+		if err != nil {
+			return nil, err
+		}
+		cookies = append(cookies, cfg.newCookie(cfg.csrfCookieName(), csrf, maxAge, false))
+	}
+	return cookies, nil
+}
+
+// SetTokenCookies 将TokenCookies写入响应，适用于net/http、Gin(c.Writer)与Echo(c.Response())
+func (j *JwtHandler) SetTokenCookies(w http.ResponseWriter, token string) error {
+	cookies, err := j.TokenCookies(token)
+	if err != nil {
+		return err
+	}
+	for _, c := range cookies {
+		http.SetCookie(w, c)
+	}
+	return nil
+}
+
+// ClearCookies 生成清除Token与CSRF Cookie的Cookie，用于登出响应
+func (j *JwtHandler) ClearCookies() []*http.Cookie {
+	cfg := j.Config.Cookie
+	return []*http.Cookie{
+		cfg.newCookie(cfg.name(), "", -1, true),
+		cfg.newCookie(cfg.csrfCookieName(), "", -1, false),
+	}
+}
+
+// ClearTokenCookies 将ClearCookies写入响应
+func (j *JwtHandler) ClearTokenCookies(w http.ResponseWriter) {
+	for _, c := range j.ClearCookies() {
+		http.SetCookie(w, c)
+	}
+}
+
+// isTokenCookie Token是否来自Cookie模式的Cookie
+func (j *JwtHandler) isTokenCookie(from TokenLookup) bool {
+	return j.Config.Cookie.Enabled && from.Type == LookupCookie && from.Name == j.Config.Cookie.name()
+}
+
+// renewalHeaders 宽限期续期需要写入的响应头：Token来自Cookie时更新Cookie，否则通过Authorization返回
+func (j *JwtHandler) renewalHeaders(res authResult) http.Header {
+	if res.newToken == "" {
+		return nil
+	}
+	header := http.Header{}
+	if res.cookies != nil {
+		for _, c := range res.cookies {
+			header.Add("Set-Cookie", c.String())
+		}
+		return header
+	}
+	header.Set("Authorization", "Bearer "+res.newToken)
+	return header
+}
+
+// checkCSRF 校验Cookie携带Token的请求：非安全方法要求CSRF头与CSRF Cookie一致且签名绑定该Token（签名双提交）
+// 未启用Cookie模式时同样校验，CSRF Cookie通过TokenCookies签发
+func (j *JwtHandler) checkCSRF(req tokenRequest, tokenString string) authResult {
+	cfg := j.Config.Cookie
+	if cfg.DisableCSRF || isSafeMethod(req.requestMethod()) {
+		return authResult{}
+	}
+
+	csrf := req.tokenValue(LookupCookie, cfg.csrfCookieName())
+	submitted := req.tokenValue(LookupHeader, cfg.csrfHeaderName())
+	if submitted == "" || csrf == "" {
+		return authFailure(http.StatusForbidden, "CSRF token required")
+	}
+	if !hmac.Equal([]byte(submitted), []byte(csrf)) || !j.validCSRFToken(csrf, tokenString) {
+		return authFailure(http.StatusForbidden, "Invalid CSRF token")
+	}
+	return authResult{}
+}
+
+// newCSRFToken 生成 随机数.签名，签名绑定Token的存储键，其他Token（包括同一用户的旧Token）的CSRF Cookie无法通过校验
+func (j *JwtHandler) newCSRFToken(token string) (string, error) {
+	nonce := make([]byte, csrfNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成CSRF Token失败: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+	return encoded + "." + j.csrfSignature(encoded, token), nil
+}
+
+func (j *JwtHandler) validCSRFToken(csrf, token string) bool {
+	nonce, sig, ok := strings.Cut(csrf, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(j.csrfSignature(nonce, token)))
+}
+
+func (j *JwtHandler) csrfSignature(nonce, token string) string {
+	mac := hmac.New(sha256.New, j.keySecret)
+	mac.Write([]byte("csrf:" + nonce + ":" + j.TokenKey(token)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// isSafeMethod 不改变状态的请求方法无需CSRF校验
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package gosjwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookieMode(t *testing.T) {
+	// cookieMode 启用Cookie模式的处理器配置
+	cookieMode := func(expires int, cookie CookieConfig) *Config {
+		cookie.Enabled = true
+		return &Config{Expires: expires, GracePeriod: 60, Cookie: cookie}
+	}
+
+	// login 模拟登录响应写入的Cookie
+	login := func(t *testing.T, h *JwtHandler, userId uint) (token, csrf string) {
+		token, err := h.ReleaseToken(userId)
+		assert.NoError(t, err)
+		cookies, err := h.TokenCookies(token)
+		assert.NoError(t, err)
+		if assert.Len(t, cookies, 2) {
+			csrf = cookies[1].Value
+		}
+		return token, csrf
+	}
+	request := func(method, token, csrfCookie, csrfHeader string) *http.Request {
+		req := httptest.NewRequest(method, "/grace", nil)
+		req.AddCookie(&http.Cookie{Name: defaultCookieName, Value: token})
+		if csrfCookie != "" {
+			req.AddCookie(&http.Cookie{Name: defaultCSRFCookieName, Value: csrfCookie})
+		}
+		if csrfHeader != "" {
+			req.Header.Set(defaultCSRFHeaderName, csrfHeader)
+		}
+		return req
+	}
+	// 在所有框架上执行同一请求
+	serve := func(t *testing.T, h *JwtHandler, req func() *http.Request, status int, body string) {
+		for _, router := range routers {
+			w := httptest.NewRecorder()
+			router.setup(h).ServeHTTP(w, req())
+			assert.Equal(t, status, w.Code, router.name)
+			assert.JSONEq(t, body, w.Body.String(), router.name)
+		}
+	}
+	cookieByName := func(cookies []*http.Cookie, name string) *http.Cookie {
+		for _, c := range cookies {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// 测试用例1: 登录Cookie为HttpOnly、Secure、SameSite，CSRF Cookie可被前端读取
+	t.Run("LoginCookies", func(t *testing.T) {
+		h := newTestHandler(t, cookieMode(3600, CookieConfig{}))
+		w := httptest.NewRecorder()
+		token, err := h.ReleaseToken(7)
+		assert.NoError(t, err)
+		assert.NoError(t, h.SetTokenCookies(w, token))
+
+		cookies := w.Result().Cookies()
+		tokenCookie := cookieByName(cookies, defaultCookieName)
+		if assert.NotNil(t, tokenCookie) {
+			assert.Equal(t, token, tokenCookie.Value)
+			assert.True(t, tokenCookie.HttpOnly)
+			assert.True(t, tokenCookie.Secure)
+			assert.Equal(t, http.SameSiteLaxMode, tokenCookie.SameSite)
+			assert.Equal(t, "/", tokenCookie.Path)
+			assert.InDelta(t, 3660, tokenCookie.MaxAge, 2, "有效期覆盖宽限期")
+		}
+		csrfCookie := cookieByName(cookies, defaultCSRFCookieName)
+		if assert.NotNil(t, csrfCookie) {
+			assert.False(t, csrfCookie.HttpOnly)
+			assert.True(t, h.validCSRFToken(csrfCookie.Value, token))
+			other, err := h.ReleaseToken(8)
+			assert.NoError(t, err)
+			assert.False(t, h.validCSRFToken(csrfCookie.Value, other), "CSRF Token绑定Token")
+		}
+
+		w = httptest.NewRecorder()
+		h.ClearTokenCookies(w)
+		for _, c := range w.Result().Cookies() {
+			assert.Equal(t, -1, c.MaxAge)
+		}
+	})
+
+	// 测试用例2: 安全方法只需Cookie，非安全方法需要CSRF双提交
+	t.Run("CSRF", func(t *testing.T) {
+		h := newTestHandler(t, cookieMode(3600, CookieConfig{}))
+		token, csrf := login(t, h, 7)
+		_, otherCsrf := login(t, h, 8)
+
+		serve(t, h, func() *http.Request { return request("GET", token, "", "") }, http.StatusOK, `{"userID":7}`)
+		serve(t, h, func() *http.Request { return request("POST", token, csrf, csrf) }, http.StatusOK, `{"userID":7}`)
+
+		serve(t, h, func() *http.Request { return request("POST", token, csrf, "") },
+			http.StatusForbidden, `{"error":"CSRF token required"}`)
+		serve(t, h, func() *http.Request { return request("DELETE", token, "", csrf) },
+			http.StatusForbidden, `{"error":"CSRF token required"}`)
+		serve(t, h, func() *http.Request { return request("PUT", token, csrf, csrf+"x") },
+			http.StatusForbidden, `{"error":"Invalid CSRF token"}`)
+		// 攻击者写入自己账号的CSRF Cookie并提交相同的值
+		serve(t, h, func() *http.Request { return request("POST", token, otherCsrf, otherCsrf) },
+			http.StatusForbidden, `{"error":"Invalid CSRF token"}`)
+	})
+
+	// 测试用例3: 通过Authorization头部携带Token的请求无需CSRF校验
+	t.Run("HeaderBypassesCSRF", func(t *testing.T) {
+		h := newTestHandler(t, cookieMode(3600, CookieConfig{}))
+		token, _ := login(t, h, 7)
+		serve(t, h, func() *http.Request {
+			req := httptest.NewRequest("POST", "/grace", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return req
+		}, http.StatusOK, `{"userID":7}`)
+	})
+
+	// 测试用例4: 宽限期续期更新Cookie而不是Authorization响应头，CSRF Token随新Token更新
+	t.Run("GraceRenewal", func(t *testing.T) {
+		for _, router := range routers {
+			// 签发与校验共用密钥，续期Token按服务端的有效期签发
+			token, csrf := login(t, newTestHandler(t, cookieMode(-1, CookieConfig{})), 7)
+			h := newTestHandler(t, cookieMode(3600, CookieConfig{}))
+
+			w := httptest.NewRecorder()
+			router.setup(h).ServeHTTP(w, request("POST", token, csrf, csrf))
+			assert.Equal(t, http.StatusOK, w.Code, router.name)
+			assert.Empty(t, w.Header().Get("Authorization"), router.name)
+
+			cookies := w.Result().Cookies()
+			renewed := cookieByName(cookies, defaultCookieName)
+			if !assert.NotNil(t, renewed, router.name) {
+				continue
+			}
+			assert.NotEqual(t, token, renewed.Value, router.name)
+			assert.True(t, renewed.HttpOnly, router.name)
+			assert.Greater(t, renewed.MaxAge, 0, router.name)
+			renewedCsrf := cookieByName(cookies, defaultCSRFCookieName)
+			if !assert.NotNil(t, renewedCsrf, router.name) {
+				continue
+			}
+			assert.NotEqual(t, csrf, renewedCsrf.Value, "%s续期更新CSRF Token", router.name)
+			assert.True(t, h.validCSRFToken(renewedCsrf.Value, renewed.Value), router.name)
+
+			// 旧CSRF Token随旧Token失效
+			w = httptest.NewRecorder()
+			router.setup(h).ServeHTTP(w, request("POST", renewed.Value, csrf, csrf))
+			assert.Equal(t, http.StatusForbidden, w.Code, router.name)
+
+			// 新Token直接通过认证，不再续期
+			w = httptest.NewRecorder()
+			router.setup(h).ServeHTTP(w, request("POST", renewed.Value, renewedCsrf.Value, renewedCsrf.Value))
+			assert.Equal(t, http.StatusOK, w.Code, router.name)
+			assert.Empty(t, w.Result().Cookies(), router.name)
+		}
+	})
+
+	// 测试用例5: 关闭CSRF后不再签发CSRF Cookie，非安全方法只需Cookie
+	t.Run("DisableCSRF", func(t *testing.T) {
+		h := newTestHandler(t, cookieMode(3600, CookieConfig{Name: "session", DisableCSRF: true, SameSite: http.SameSiteStrictMode}))
+		token, err := h.ReleaseToken(7)
+		assert.NoError(t, err)
+		cookies, err := h.TokenCookies(token)
+		assert.NoError(t, err)
+		if assert.Len(t, cookies, 1) {
+			assert.Equal(t, "session", cookies[0].Name)
+			assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+		}
+
+		serve(t, h, func() *http.Request {
+			req := httptest.NewRequest("POST", "/grace", nil)
+			req.AddCookie(&http.Cookie{Name: "session", Value: token})
+			return req
+		}, http.StatusOK, `{"userID":7}`)
+	})
+
+	// cookieRequest 通过指定名称的Cookie携带Token
+	cookieRequest := func(method, name, token, csrf string) *http.Request {
+		req := httptest.NewRequest(method, "/grace", nil)
+		req.AddCookie(&http.Cookie{Name: name, Value: token})
+		if csrf != "" {
+			req.AddCookie(&http.Cookie{Name: defaultCSRFCookieName, Value: csrf})
+			req.Header.Set(defaultCSRFHeaderName, csrf)
+		}
+		return req
+	}
+
+	// 测试用例6: TokenLookup中其他名称的Cookie来源同样需要CSRF校验
+	t.Run("CustomCookieLookup", func(t *testing.T) {
+		config := cookieMode(3600, CookieConfig{})
+		config.TokenLookup = []TokenLookup{{Type: LookupCookie, Name: "legacy_token"}}
+		h := newTestHandler(t, config)
+		token, csrf := login(t, h, 7)
+
+		serve(t, h, func() *http.Request { return cookieRequest("POST", "legacy_token", token, "") },
+			http.StatusForbidden, `{"error":"CSRF token required"}`)
+		serve(t, h, func() *http.Request { return cookieRequest("POST", "legacy_token", token, csrf) },
+			http.StatusOK, `{"userID":7}`)
+	})
+
+	// 测试用例7: 未启用Cookie模式时，来自Cookie的Token同样需要CSRF校验
+	t.Run("CookieLookupWithoutCookieMode", func(t *testing.T) {
+		h := newTestHandler(t, &Config{
+			Expires:     3600,
+			TokenLookup: []TokenLookup{{Type: LookupCookie, Name: "jwt"}},
+		})
+		token, csrf := login(t, h, 7)
+
+		serve(t, h, func() *http.Request { return cookieRequest("GET", "jwt", token, "") },
+			http.StatusOK, `{"userID":7}`)
+		serve(t, h, func() *http.Request { return cookieRequest("DELETE", "jwt", token, "") },
+			http.StatusForbidden, `{"error":"CSRF token required"}`)
+		serve(t, h, func() *http.Request { return cookieRequest("DELETE", "jwt", token, csrf) },
+			http.StatusOK, `{"userID":7}`)
+	})
+}
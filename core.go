@@ -9,85 +9,126 @@
 package gosjwt
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/zjguoxin/goscache/v2/cache"
 )
 
-type gracePeriodToken struct {
-	deadline time.Time // 绝对截止时间
-	newToken string
-}
+// ErrTokenRevoked Token已被撤销
+var ErrTokenRevoked = errors.New("token已被撤销")
+
+// ErrStoreTimeout 存储操作超时，可能由ctx的截止时间或Config.StoreTimeout触发
+var ErrStoreTimeout = errors.New("存储操作超时")
 
 type JwtHandler struct {
-	Config      *Config
-	tokenCache  cache.CacheInterface
-	blacklist   cache.CacheInterface
-	graceTokens map[string]*gracePeriodToken // 记录宽限期内的Token
-	graceMutex  sync.Mutex
+	Config    *Config
+	store     Store              // Token存储
+	scheduler *expiryScheduler   // 宽限期到期调度器
+	epoch     atomic.Int64       // 全局撤销时间点(Unix秒)，早于该时间签发的Token均无效
+	revoked   *revocationSet     // 本地撤销集合，未启用时为nil
+	filter    *revocationFilter  // 撤销检查布隆过滤器，未启用时为nil
+	notifier  RevocationNotifier // 跨实例撤销事件广播，存储不支持时为nil
+	resync    chan struct{}      // 撤销事件订阅重新建立后立即全量校准的信号
+	keySecret []byte             // 存储键HMAC密钥
+
+	quit      chan struct{}  // 关闭信号
+	workers   sync.WaitGroup // 后台协程
+	closeOnce sync.Once
+	closeErr  error
 }
 
 func NewJwtHandler(config *Config) (*JwtHandler, error) {
-	// 初始化Token缓存
-	tokenCache, err := createCache(config.Cache, "token:")
-	if err != nil {
-		return nil, fmt.Errorf("初始化Token缓存失败: %v", err)
+	if err := validateTokenLookup(config.TokenLookup); err != nil {
+		return nil, err
 	}
 
-	// 初始化黑名单缓存
-	blacklist, err := createCache(config.Cache, "blacklist:")
-	if err != nil {
-		return nil, fmt.Errorf("初始化黑名单缓存失败: %v", err)
+	// 初始化存储，未指定时按缓存配置创建内置存储
+	store := config.Store
+	if store == nil {
+		var err error
+		store, err = newCacheStore(config.Cache)
+		if err != nil {
+			return nil, fmt.Errorf("初始化存储失败: %v", err)
+		}
+	}
+	if config.Breaker.Enabled {
+		store = newBreakerStore(store, config.Breaker)
+	}
+	if err := checkRevocationCache(config, store); err != nil {
+		if config.Store == nil {
+			_ = store.Close()
+		}
+		return nil, err
 	}
 
-	// 初始化宽限期清理定时器
 	handler := &JwtHandler{
-		Config:      config,
-		tokenCache:  tokenCache,
-		blacklist:   blacklist,
-		graceTokens: make(map[string]*gracePeriodToken),
+		Config:    config,
+		store:     store,
+		keySecret: tokenKeySecret(config),
+		quit:      make(chan struct{}),
 	}
 
-	// 启动后台协程定期清理过期的宽限期Token
-	go handler.startGracePeriodCleaner()
+	// 由统一调度器负责宽限期到期与黑名单转入
+	handler.scheduler = newExpiryScheduler(handler.expireGraceToken)
+
+	// 加载全局撤销时间点，共享存储时定期同步
+	handler.syncEpoch()
+	if interval := handler.epochSyncInterval(); interval > 0 {
+		handler.startTicker(interval, handler.syncEpoch)
+	}
+
+	// 本地撤销集合与布隆过滤器
+	handler.initRevocationCache()
 
 	return handler, nil
 }
 
-// createCache 创建具体缓存实例
-func createCache(cfg CacheConfig, suffix string) (cache.CacheInterface, error) {
-	if cfg.Type == "redis" {
-		redisCache, err := cache.NewCache(cache.CacheTypeRedis,
-			cache.WithRedisConfig(cfg.RedisAddr, cfg.RedisPass, cfg.Prefix+suffix, cfg.RedisDB),
-			cache.WithHashExpiry(30*time.Minute),
-			cache.WithPoolConfig(100, 20), // 增加连接池大小
-			cache.WithHashExpiry(30*time.Minute),
-		)
-		if err != nil {
-			fmt.Println("Redis连接失败，回退到内存缓存:", err)
-			// 回退到内存缓存
-			memCache, err := cache.NewCache(cache.CacheTypeMemory)
-			if err != nil {
-				return nil, fmt.Errorf("内存缓存初始化失败: %v", err)
-			}
-			return memCache, nil
-		}
-		return redisCache, nil
+// Store 返回处理器使用的存储，可用于会话等扩展数据
+func (j *JwtHandler) Store() Store {
+	return j.store
+}
+
+// storeContext 为单次存储操作附加Config.StoreTimeout
+func (j *JwtHandler) storeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if j.Config.StoreTimeout > 0 {
+		return context.WithTimeout(ctx, time.Duration(j.Config.StoreTimeout)*time.Millisecond)
 	}
+	return context.WithCancel(ctx)
+}
 
-	// 默认使用内存缓存
-	memCache, err := cache.NewCache(cache.CacheTypeMemory)
-	if err != nil {
-		return nil, fmt.Errorf("内存缓存初始化失败: %v", err)
+// storeError 超时转换为ErrStoreTimeout，同时保留原始错误
+func storeError(err error) error {
+	if err == nil || errors.Is(err, ErrStoreTimeout) {
+		return err
 	}
-	return memCache, nil
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrStoreTimeout, err)
+	}
+	return err
+}
+
+// isContextError 是否为超时或取消，此类错误需要返回给调用方而不是按存储故障放行
+func isContextError(err error) bool {
+	return errors.Is(err, ErrStoreTimeout) || errors.Is(err, context.Canceled)
 }
 
 // ReleaseToken 生成并缓存Token
 func (j *JwtHandler) ReleaseToken(userId uint) (string, error) {
+	return j.ReleaseTokenContext(context.Background(), userId)
+}
+
+// ReleaseTokenContext 生成并缓存Token，存储操作受ctx控制
+func (j *JwtHandler) ReleaseTokenContext(ctx context.Context, userId uint) (string, error) {
+	if j.isClosed() {
+		return "", ErrHandlerClosed
+	}
 	expirationTime := time.Now().Add(time.Duration(j.Config.Expires) * time.Second)
 
 	claims := &Claims{
@@ -104,14 +145,14 @@ func (j *JwtHandler) ReleaseToken(userId uint) (string, error) {
 		return "", fmt.Errorf("生成Token失败: %v", err)
 	}
 
-	// 存储到缓存
-	userData := map[string]interface{}{
-		"userId":    userId,
-		"expiresAt": claims.ExpiresAt,
-	}
-	err = j.tokenCache.SetHash(tokenString, userData, time.Until(expirationTime))
-	if err != nil {
-		return "", fmt.Errorf("缓存Token失败: %v", err)
+	// 存储Token元数据，签发即过期的Token无需缓存
+	if ttl := time.Until(expirationTime); ttl > 0 {
+		rec := TokenRecord{UserId: userId, IssuedAt: claims.IssuedAt, ExpiresAt: claims.ExpiresAt}
+		sctx, cancel := j.storeContext(ctx)
+		defer cancel()
+		if err := j.store.SetToken(sctx, j.TokenKey(tokenString), rec, ttl); err != nil {
+			return "", fmt.Errorf("缓存Token失败: %w", storeError(err))
+		}
 	}
 
 	return tokenString, nil
@@ -119,34 +160,49 @@ func (j *JwtHandler) ReleaseToken(userId uint) (string, error) {
 
 // ParseToken 解析并验证Token
 func (j *JwtHandler) ParseToken(tokenString string) (*jwt.Token, *Claims, error) {
+	return j.ParseTokenContext(context.Background(), tokenString)
+}
+
+// ParseTokenContext 解析并验证Token，存储超时返回ErrStoreTimeout
+func (j *JwtHandler) ParseTokenContext(ctx context.Context, tokenString string) (*jwt.Token, *Claims, error) {
 	// 检查黑名单
-	if j.blacklist != nil {
-		if exists, err := j.blacklist.Exists(tokenString); err == nil && exists {
-			return nil, nil, fmt.Errorf("token已被撤销")
-		}
+	revoked, err := j.isTokenRevoked(ctx, tokenString)
+	if err != nil {
+		return nil, nil, err
 	}
+	if revoked {
+		return nil, nil, ErrTokenRevoked
+	}
+	return j.parseUnrevoked(ctx, tokenString)
+}
 
-	// 尝试从缓存获取
-	if cachedData, err := j.tokenCache.GetHash(tokenString); err == nil && len(cachedData) > 0 {
-		if userId, ok := cachedData["userId"].(uint); ok {
-			if expiresAt, ok := cachedData["expiresAt"].(int64); ok && expiresAt > time.Now().Unix() {
-				claims := &Claims{
-					UserId: userId,
-					StandardClaims: jwt.StandardClaims{
-						ExpiresAt: expiresAt,
-						Issuer:    j.Config.Issuer,
-					},
-				}
-				return &jwt.Token{Valid: true}, claims, nil
-			}
+// parseUnrevoked 解析已通过撤销检查的Token
+func (j *JwtHandler) parseUnrevoked(ctx context.Context, tokenString string) (*jwt.Token, *Claims, error) {
+	// 尝试从缓存获取，未命中或读取失败时回落到签名验证
+	sctx, cancel := j.storeContext(ctx)
+	rec, found, err := j.store.GetToken(sctx, j.TokenKey(tokenString))
+	cancel()
+	if err = storeError(err); isContextError(err) {
+		return nil, nil, err
+	}
+	if err == nil && found && rec.ExpiresAt+int64(j.Config.Leeway) > time.Now().Unix() {
+		claims := &Claims{
+			UserId: rec.UserId,
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: rec.ExpiresAt,
+				IssuedAt:  rec.IssuedAt,
+				Issuer:    j.Config.Issuer,
+			},
 		}
+		if j.isIssuedBeforeEpoch(claims) {
+			return nil, nil, errIssuedBeforeEpoch
+		}
+		return &jwt.Token{Valid: true}, claims, nil
 	}
 
 	// 正常解析流程
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (i interface{}, err error) {
-		return j.Config.SigningKey, nil
-	})
+	token, err := j.parseClaims(tokenString, claims)
 
 	if err != nil {
 		return nil, nil, err
@@ -156,6 +212,10 @@ func (j *JwtHandler) ParseToken(tokenString string) (*jwt.Token, *Claims, error)
 		return nil, nil, fmt.Errorf("无效的token")
 	}
 
+	if j.isIssuedBeforeEpoch(claims) {
+		return nil, nil, errIssuedBeforeEpoch
+	}
+
 	// // 更新缓存
 	// userData := map[string]interface{}{
 	// 	"userId":    claims.UserId,
@@ -173,62 +233,136 @@ func (j *JwtHandler) ParseToken(tokenString string) (*jwt.Token, *Claims, error)
 
 // RevokeToken 撤销Token
 func (j *JwtHandler) RevokeToken(tokenString string) error {
+	return j.RevokeTokenContext(context.Background(), tokenString)
+}
+
+// RevokeTokenContext 撤销Token，存储操作受ctx控制
+func (j *JwtHandler) RevokeTokenContext(ctx context.Context, tokenString string) error {
 	if tokenString == "" {
 		return fmt.Errorf("token不能为空")
 	}
-	if j.blacklist == nil {
-		return fmt.Errorf("黑名单缓存未初始化")
-	}
 	claims := &Claims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (i interface{}, err error) {
-		return j.Config.SigningKey, nil
-	})
+	_, err := j.parseClaims(tokenString, claims)
 
 	// 即使解析失败（如过期）也加入黑名单
 	if err != nil && !isExpiredError(err) {
 		return err
 	}
 	// 加入黑名单
-	remaining := time.Hour * 24 // 默认24小时
-	if claims.ExpiresAt > 0 {
-		remaining = time.Until(time.Unix(claims.ExpiresAt, 0))
-		if remaining < time.Minute {
-			remaining = time.Minute
+	key := j.TokenKey(tokenString)
+	ttl := j.revocationTTL(claims)
+	sctx, cancel := j.storeContext(ctx)
+	defer cancel()
+	if err := j.store.Revoke(sctx, key, ttl); err != nil {
+		return storeError(err)
+	}
+	j.publishRevocation(sctx, key, ttl)
+	return nil
+}
+
+const (
+	defaultRevocationTTL = 24 * time.Hour // 无法获知过期时间时的保留时长
+	minRevocationTTL     = time.Minute    // 撤销记录最短保留时长
+)
+
+// revocationTTL 计算黑名单记录的保留时长
+// 默认覆盖Token剩余有效期、宽限期与时钟偏差，此后Token无论如何都不会再被接受
+func (j *JwtHandler) revocationTTL(claims *Claims) time.Duration {
+	if j.Config.RevocationTTLFunc != nil {
+		if ttl := j.Config.RevocationTTLFunc(claims); ttl > 0 {
+			return ttl
 		}
 	}
 
-	return j.blacklist.Set(tokenString, true, 100*time.Second)
-}
+	if claims.ExpiresAt <= 0 {
+		if j.Config.RevocationDefaultTTL > 0 {
+			return time.Duration(j.Config.RevocationDefaultTTL) * time.Second
+		}
+		return defaultRevocationTTL
+	}
 
-// 启动宽限期Token清理器
-func (j *JwtHandler) startGracePeriodCleaner() {
-	if j.Config.BlacklistCleanDuration <= 0 {
-		return
+	ttl := time.Until(j.acceptDeadline(claims))
+	if ttl < minRevocationTTL {
+		ttl = minRevocationTTL
 	}
-	ticker := time.NewTicker(time.Duration(j.Config.BlacklistCleanDuration) * time.Minute) // 每分钟检查一次
-	defer ticker.Stop()
+	return ttl
+}
 
-	for range ticker.C {
-		j.cleanExpiredGraceTokens()
+// parseClaims 验证签名，并按Leeway放宽exp、nbf与iat的校验
+// 返回的错误与jwt-go一致，过期时带有ValidationErrorExpired标记
+func (j *JwtHandler) parseClaims(tokenString string, claims *Claims) (*jwt.Token, error) {
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (i interface{}, err error) {
+		return j.Config.SigningKey, nil
+	})
+	if err != nil {
+		return token, err
+	}
+	if err := j.validateClaims(claims); err != nil {
+		token.Valid = false
+		return token, err
 	}
+	return token, nil
 }
 
-// 清理过期的宽限期Token
-func (j *JwtHandler) cleanExpiredGraceTokens() {
-	j.graceMutex.Lock()
-	defer j.graceMutex.Unlock()
+// validateClaims 按时钟偏差校验时间声明
+func (j *JwtHandler) validateClaims(claims *Claims) error {
+	now := time.Now().Unix()
+	leeway := int64(j.Config.Leeway)
 
-	now := time.Now()
-	for tokenStr, gpToken := range j.graceTokens {
-		if now.Sub(gpToken.deadline) > time.Duration(j.Config.GracePeriod)*time.Second {
-			delete(j.graceTokens, tokenStr)
-			_ = j.RevokeToken(tokenStr) // 加入黑名单
+	var vErr *jwt.ValidationError
+	add := func(text string, flag uint32) {
+		if vErr == nil {
+			vErr = jwt.NewValidationError(text, flag)
+			return
 		}
+		vErr.Errors |= flag
+	}
+	if !claims.VerifyExpiresAt(now-leeway, false) {
+		add(fmt.Sprintf("token is expired by %v", time.Duration(now-claims.ExpiresAt)*time.Second), jwt.ValidationErrorExpired)
+	}
+	if !claims.VerifyIssuedAt(now+leeway, false) {
+		add("Token used before issued", jwt.ValidationErrorIssuedAt)
+	}
+	if !claims.VerifyNotBefore(now+leeway, false) {
+		add("token is not valid yet", jwt.ValidationErrorNotValidYet)
+	}
+	if vErr != nil {
+		return vErr
+	}
+	return nil
+}
+
+// acceptDeadline Token最后可能被接受的时间：过期时间+宽限期+时钟偏差
+func (j *JwtHandler) acceptDeadline(claims *Claims) time.Time {
+	return time.Unix(claims.ExpiresAt, 0).
+		Add(time.Duration(j.Config.GracePeriod) * time.Second).
+		Add(time.Duration(j.Config.Leeway) * time.Second)
+}
+
+const (
+	graceExpirySlack    = time.Second // 宽限期截止后额外等待的时间，避免与截止时刻的请求竞争
+	graceStateRetention = time.Minute // 宽限期状态在截止后额外保留的时间，覆盖调度延迟
+)
+
+// 宽限期到期：移出宽限期记录并加入黑名单
+// 调度器只在进程内保存原始Token，存储中均使用其HMAC键
+func (j *JwtHandler) expireGraceToken(tokenStr string) {
+	ctx, cancel := j.storeContext(context.Background())
+	defer cancel()
+
+	key := j.TokenKey(tokenStr)
+	state, found, err := j.store.GetGrace(ctx, key)
+	if err != nil || (found && !time.Now().After(state.Deadline)) {
+		return
 	}
+	_ = j.store.DeleteGrace(ctx, key)
+	_ = j.RevokeTokenContext(ctx, tokenStr) // 加入黑名单
 }
 
-func (j *JwtHandler) Close() {
-	j.tokenCache.Close()
+// SchedulerStats 返回宽限期调度器的队列指标
+func (j *JwtHandler) SchedulerStats() SchedulerStats {
+	return j.scheduler.Stats()
 }
 
 // 检查是否是Token过期错误
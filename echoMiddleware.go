@@ -0,0 +1,31 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 23:41:18
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 23:41:18
+ * Description: Echo认证中间件
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// EchoMiddleware 创建Echo认证中间件
+// 认证通过后通过c.Get("userID")读取用户ID，请求ctx中同时写入Claims；失败时的状态码与响应体与GinMiddleware一致
+func (j *JwtHandler) EchoMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			res := j.authenticate(req.Context(), httpTokenRequest{r: req, maxForm: j.maxFormSize()})
+			if res.status != 0 {
+				return c.Blob(res.status, jsonContentType, errorBody(res.message))
+			}
+			writeRenewal(c.Response().Header(), j.renewalHeaders(res))
+			c.SetRequest(req.WithContext(withClaims(req.Context(), res.claims)))
+			c.Set(UserIdContextKey, res.claims.UserId)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 11:20:03
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 11:20:03
+ * Description: 全局撤销时间点
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errIssuedBeforeEpoch 签发时间早于全局撤销时间点
+var errIssuedBeforeEpoch = fmt.Errorf("%w: 签发时间早于全局撤销时间点", ErrTokenRevoked)
+
+// ErrEpochInFuture 全局撤销时间点晚于当前时间加允许的时钟偏差
+// 时间点只升不降，误写入的未来时间会让此后签发的Token全部失效且无法撤回，因此直接拒绝
+var ErrEpochInFuture = errors.New("全局撤销时间点不能晚于当前时间")
+
+// maxEpochSkew 全局撤销时间点允许超前当前时间的最大偏差
+const maxEpochSkew = time.Minute
+
+// revocationEpochKey 全局撤销时间点在黑名单中的键
+const revocationEpochKey = "__revocation_epoch__"
+
+// defaultEpochSyncInterval 共享缓存下同步全局撤销时间点的默认间隔
+const defaultEpochSyncInterval = time.Second
+
+// RevokeIssuedBefore 撤销所有在t之前签发的Token
+// 时间点只会提高不会降低，签发时间按秒记录，t所在的整秒内签发的Token同样失效
+func (j *JwtHandler) RevokeIssuedBefore(t time.Time) error {
+	return j.RevokeIssuedBeforeContext(context.Background(), t)
+}
+
+// RevokeIssuedBeforeContext 撤销所有在t之前签发的Token，存储操作受ctx控制
+func (j *JwtHandler) RevokeIssuedBeforeContext(ctx context.Context, t time.Time) error {
+	if err := checkEpochTime(t); err != nil {
+		return err
+	}
+	ctx, cancel := j.storeContext(ctx)
+	defer cancel()
+	epoch, err := j.store.RaiseEpoch(ctx, epochSeconds(t))
+	if err != nil {
+		return storeError(err)
+	}
+	j.storeEpoch(epoch)
+	j.publishEpoch(ctx, epoch)
+	return nil
+}
+
+// RevocationEpoch 返回当前生效的全局撤销时间点，未设置时为零值
+func (j *JwtHandler) RevocationEpoch() time.Time {
+	epoch := j.epoch.Load()
+	if epoch <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(epoch, 0)
+}
+
+// RevokeAllHandler 管理接口：撤销指定时间之前签发的全部Token
+// 可选参数 before 支持 RFC3339 或 Unix 秒，缺省为当前时间，晚于当前时间一分钟以上时返回400；调用方需自行做好管理员鉴权
+func (j *JwtHandler) RevokeAllHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		before := time.Now()
+		if raw := c.PostForm("before"); raw != "" {
+			t, err := ParseEpochTime(raw)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid before parameter"})
+				return
+			}
+			before = t
+		}
+		if checkEpochTime(before) != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "before must not be in the future"})
+			return
+		}
+
+		if err := j.RevokeIssuedBeforeContext(c.Request.Context(), before); err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke tokens"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"epoch": j.RevocationEpoch().Unix()})
+	}
+}
+
+// RaiseRevocationEpoch 不启动处理器，直接写入Redis中的全局撤销时间点并广播，供命令行工具使用
+// 订阅撤销事件的实例立即生效，其他实例在同步间隔内生效；内存缓存无法跨进程共享，因此不支持
+func RaiseRevocationEpoch(config *Config, t time.Time) (time.Time, error) {
+	if config.Cache.Type != "redis" {
+		return time.Time{}, fmt.Errorf("全局撤销时间点仅支持通过Redis缓存写入")
+	}
+	if err := checkEpochTime(t); err != nil {
+		return time.Time{}, err
+	}
+	store, err := newRedisStore(config.Cache)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("初始化存储失败: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	epoch, err := store.RaiseEpoch(ctx, epochSeconds(t))
+	if err != nil {
+		return time.Time{}, err
+	}
+	// 广播失败时各实例仍会在同步间隔内读取到新的时间点
+	_ = store.PublishRevocation(ctx, RevocationEvent{Type: "epoch", Epoch: epoch})
+	return time.Unix(epoch, 0), nil
+}
+
+// isIssuedBeforeEpoch 签发时间是否早于全局撤销时间点，仅读取本地原子变量
+func (j *JwtHandler) isIssuedBeforeEpoch(claims *Claims) bool {
+	epoch := j.epoch.Load()
+	return epoch > 0 && claims.IssuedAt < epoch
+}
+
+// syncEpoch 从共享缓存同步全局撤销时间点
+func (j *JwtHandler) syncEpoch() {
+	ctx, cancel := j.storeContext(context.Background())
+	defer cancel()
+	if epoch, err := j.store.GetEpoch(ctx); err == nil {
+		j.storeEpoch(epoch)
+	}
+}
+
+// storeEpoch 本地时间点只升不降
+func (j *JwtHandler) storeEpoch(epoch int64) {
+	for {
+		current := j.epoch.Load()
+		if epoch <= current || j.epoch.CompareAndSwap(current, epoch) {
+			return
+		}
+	}
+}
+
+// epochSyncInterval 共享存储时的同步间隔，内置内存与文件存储仅本进程可见无需同步
+func (j *JwtHandler) epochSyncInterval() time.Duration {
+	switch baseStore(j.store).(type) {
+	case *cacheStore, *boundedStore, *FileStore:
+		return 0
+	}
+	if j.Config.EpochSyncInterval > 0 {
+		return time.Duration(j.Config.EpochSyncInterval) * time.Second
+	}
+	return defaultEpochSyncInterval
+}
+
+// epochSeconds 向上取整到秒
+func epochSeconds(t time.Time) int64 {
+	epoch := t.Unix()
+	if t.Nanosecond() > 0 {
+		epoch++
+	}
+	return epoch
+}
+
+// checkEpochTime 拒绝晚于当前时间加最大偏差的时间点，如误将毫秒时间戳当作秒
+func checkEpochTime(t time.Time) error {
+	if t.After(time.Now().Add(maxEpochSkew)) {
+		return fmt.Errorf("%w: %s", ErrEpochInFuture, t.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ParseEpochTime 解析 RFC3339 或 Unix 秒，管理接口与命令行工具共用
+func ParseEpochTime(raw string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
@@ -0,0 +1,139 @@
+package gosjwt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationEpoch(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 撤销时间点之前签发的Token全部失效
+	t.Run("RevokeIssuedBefore", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Issuer: "test-issuer", Expires: 3600, GracePeriod: 60})
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeIssuedBefore(time.Now()))
+
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+
+		w := performRequest(setupGraceRouter(handler), token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "Token revoked")
+
+		// 撤销时间点之后签发的Token不受影响，等到撤销时间点所在的整秒之后再签发
+		time.Sleep(time.Until(handler.RevocationEpoch()))
+		fresh, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		_, _, err = handler.ParseToken(fresh)
+		assert.NoError(t, err)
+	})
+
+	// 测试用例2: 过期Token不能借宽限期绕过全局撤销
+	t.Run("ExpiredTokenNoRenewal", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Issuer: "test-issuer", Expires: -1, GracePeriod: 60})
+
+		token, err := handler.ReleaseToken(2)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeIssuedBefore(time.Now()))
+
+		w := performRequest(setupGraceRouter(handler), token)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("Authorization"))
+	})
+
+	// 测试用例3: 时间点只升不降，并能从共享缓存同步
+	t.Run("MonotonicAndSync", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Issuer: "test-issuer", Expires: 3600, GracePeriod: 60})
+
+		later := time.Now().Truncate(time.Second)
+		assert.NoError(t, handler.RevokeIssuedBefore(later))
+		assert.NoError(t, handler.RevokeIssuedBefore(later.Add(-time.Hour)))
+		assert.True(t, handler.RevocationEpoch().Equal(later))
+
+		// 模拟其他实例写入更晚的时间点
+		evenLater := later.Add(30 * time.Second)
+		_, err := handler.store.RaiseEpoch(ctx, evenLater.Unix())
+		assert.NoError(t, err)
+		handler.syncEpoch()
+		assert.True(t, handler.RevocationEpoch().Equal(evenLater))
+	})
+
+	// 测试用例4: 管理接口
+	t.Run("AdminHandler", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Issuer: "test-issuer", Expires: 3600, GracePeriod: 60})
+
+		r := gin.New()
+		r.POST("/admin/revoke-all", handler.RevokeAllHandler())
+
+		before := time.Now().Add(30 * time.Second).Unix()
+		req := httptest.NewRequest("POST", "/admin/revoke-all", strings.NewReader("before="+strconv.FormatInt(before, 10)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), strconv.FormatInt(before, 10))
+		assert.Equal(t, before, handler.RevocationEpoch().Unix())
+
+		req = httptest.NewRequest("POST", "/admin/revoke-all", strings.NewReader("before=yesterday"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	// 测试用例5: 命令行写入的时间点立即广播到订阅的实例
+	t.Run("RaiseAndPublish", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.LocalRevocation = true
+		config.EpochSyncInterval = 3600 // 排除定期同步
+		handler := newTestHandler(t, config)
+
+		_, err := RaiseRevocationEpoch(&Config{Cache: CacheConfig{Type: "file"}}, time.Now())
+		assert.Error(t, err)
+
+		before, err := ParseEpochTime(strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10))
+		assert.NoError(t, err)
+		epoch, err := RaiseRevocationEpoch(config, before)
+		assert.NoError(t, err)
+		assert.Equal(t, before.Unix(), epoch.Unix())
+		assert.Eventually(t, func() bool {
+			return handler.RevocationEpoch().Equal(epoch)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	// 测试用例6: 拒绝晚于当前时间的撤销时间点，避免误操作永久拒绝所有Token
+	t.Run("RejectFutureEpoch", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Issuer: "test-issuer", Expires: 3600, GracePeriod: 60})
+
+		assert.ErrorIs(t, handler.RevokeIssuedBefore(time.Now().Add(time.Hour)), ErrEpochInFuture)
+		assert.True(t, handler.RevocationEpoch().IsZero())
+
+		// 毫秒时间戳被当作秒解析
+		r := gin.New()
+		r.POST("/admin/revoke-all", handler.RevokeAllHandler())
+		req := httptest.NewRequest("POST", "/admin/revoke-all", strings.NewReader("before="+strconv.FormatInt(time.Now().UnixMilli(), 10)))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"error":"before must not be in the future"}`, w.Body.String())
+		assert.True(t, handler.RevocationEpoch().IsZero())
+
+		mr := miniredis.RunT(t)
+		_, err := RaiseRevocationEpoch(newRedisTestConfig(mr.Addr()), time.Now().Add(time.Hour))
+		assert.ErrorIs(t, err, ErrEpochInFuture)
+		assert.False(t, mr.Exists("test_blacklist:"+revocationEpochKey))
+	})
+}
@@ -9,7 +9,13 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	gosjwt "github.com/zjguoxin/gos-jwt"
@@ -47,7 +53,26 @@ func main() {
 	// 使用中间件
 	// r.Use(JwtHandler.GinMiddleware())
 
-	r.Run(":8080")
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("服务启动失败: %v", err)
+		}
+	}()
+
+	// 优雅退出：先停止接收请求，再关闭JWT处理器
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("服务关闭失败: %v", err)
+	}
+	if err := JwtHandler.Shutdown(ctx); err != nil {
+		log.Printf("JWT处理器关闭失败: %v", err)
+	}
 	// userID := uint(12345)
 	// tokenString, err := jwtHandler.ReleaseToken(userID)
 	// if err != nil {
@@ -0,0 +1,151 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 21:48:12
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 21:48:12
+ * Description: 撤销记录与会话的导出导入
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ErrExportUnsupported 存储未实现StateExporter
+var ErrExportUnsupported = errors.New("存储不支持导出")
+
+// stateKindOrder 导出文件中各类记录的顺序
+var stateKindOrder = map[string]int{StateEpoch: 0, StateRevocation: 1, StateGrace: 2, StateSession: 3}
+
+// ExportState 以JSON Lines格式导出全局撤销时间点、撤销记录、宽限期状态与会话，每行一个StateRecord，返回写入的行数
+// 过期时间按绝对时间保存，导入时换算为剩余时长；导出为整体扫描，不受StoreTimeout限制，由ctx控制
+func (j *JwtHandler) ExportState(ctx context.Context, w io.Writer) (int, error) {
+	exporter, ok := j.store.(StateExporter)
+	if !ok {
+		return 0, ErrExportUnsupported
+	}
+	records, err := exporter.ExportState(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("导出存储失败: %w", storeError(err))
+	}
+	epoch, err := j.store.GetEpoch(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("读取全局撤销时间点失败: %w", storeError(err))
+	}
+	if epoch > 0 {
+		records = append(records, StateRecord{Kind: StateEpoch, Epoch: epoch})
+	}
+
+	// 按类型与键排序，便于比对两次导出的差异
+	sort.Slice(records, func(a, b int) bool {
+		ra, rb := records[a], records[b]
+		if ra.Kind != rb.Kind {
+			return stateKindOrder[ra.Kind] < stateKindOrder[rb.Kind]
+		}
+		return stateRecordID(ra) < stateRecordID(rb)
+	})
+
+	enc := json.NewEncoder(w)
+	for i, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return i, fmt.Errorf("写入导出文件失败: %v", err)
+		}
+	}
+	return len(records), nil
+}
+
+// ImportState 读取ExportState的输出并写入当前存储，返回写入的记录数
+// 已过期的记录被跳过；撤销记录与会话覆盖已有值，宽限期状态仅在不存在时写入，全局撤销时间点只升不降
+// 导入的撤销与全局撤销时间点同步到本地撤销集合并广播给其他实例
+func (j *JwtHandler) ImportState(ctx context.Context, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	imported := 0
+	for n := 1; ; n++ {
+		var rec StateRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return imported, nil
+		} else if err != nil {
+			return imported, fmt.Errorf("第%d条记录解析失败: %v", n, err)
+		}
+
+		written, err := j.importRecord(ctx, rec)
+		if err != nil {
+			return imported, fmt.Errorf("第%d条记录导入失败: %w", n, err)
+		}
+		if written {
+			imported++
+		}
+	}
+}
+
+// importRecord 写入单条记录，返回是否实际写入
+func (j *JwtHandler) importRecord(ctx context.Context, rec StateRecord) (bool, error) {
+	var ttl time.Duration
+	if rec.ExpiresAt > 0 {
+		if ttl = time.Until(time.UnixMilli(rec.ExpiresAt)); ttl <= 0 {
+			return false, nil
+		}
+	}
+
+	sctx, cancel := j.storeContext(ctx)
+	defer cancel()
+
+	switch rec.Kind {
+	case StateRevocation:
+		if rec.Key == "" {
+			return false, fmt.Errorf("撤销记录缺少键")
+		}
+		if err := j.store.Revoke(sctx, rec.Key, ttl); err != nil {
+			return false, storeError(err)
+		}
+		localTTL := ttl
+		if localTTL <= 0 {
+			localTTL = defaultRevocationTTL // 不过期的记录在本地按默认时长保留，由全量校准续期
+		}
+		j.publishRevocation(sctx, rec.Key, localTTL)
+		return true, nil
+
+	case StateEpoch:
+		epoch, err := j.store.RaiseEpoch(sctx, rec.Epoch)
+		if err != nil {
+			return false, storeError(err)
+		}
+		j.storeEpoch(epoch)
+		j.publishEpoch(sctx, epoch)
+		return true, nil
+
+	case StateGrace:
+		if rec.Key == "" || rec.Grace == nil {
+			return false, fmt.Errorf("宽限期状态缺少键或内容")
+		}
+		_, stored, err := j.store.PutGrace(sctx, rec.Key, *rec.Grace, ttl)
+		return stored, storeError(err)
+
+	case StateSession:
+		if rec.Session == nil {
+			return false, fmt.Errorf("会话记录缺少内容")
+		}
+		if err := j.store.SetSession(sctx, *rec.Session, ttl); err != nil {
+			return false, storeError(err)
+		}
+		return true, nil
+
+	default:
+		return false, fmt.Errorf("未知的记录类型: %s", rec.Kind)
+	}
+}
+
+// stateRecordID 记录在同类中的排序键
+func stateRecordID(rec StateRecord) string {
+	if rec.Session != nil {
+		return rec.Session.ID
+	}
+	return rec.Key
+}
@@ -0,0 +1,122 @@
+package gosjwt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 从内存迁移到Redis，保留剩余过期时间
+	t.Run("MemoryToRedis", func(t *testing.T) {
+		src := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "memory"}})
+		token, err := src.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, src.RevokeToken(token))
+		assert.NoError(t, src.RevokeIssuedBefore(time.Unix(1700000000, 0)))
+		deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+		_, _, err = src.Store().PutGrace(ctx, "grace-key", GraceState{Deadline: deadline, NewTokenKey: "new-key"}, time.Minute)
+		assert.NoError(t, err)
+		sess := Session{ID: "sess-1", UserId: 1, Data: map[string]string{"role": "admin"}, ExpiresAt: deadline}
+		assert.NoError(t, src.Store().SetSession(ctx, sess, time.Hour))
+
+		var buf bytes.Buffer
+		exported, err := src.ExportState(ctx, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, exported)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 4)
+		assert.Contains(t, lines[0], `"kind":"epoch"`, "全局撤销时间点位于首行")
+
+		mr := miniredis.RunT(t)
+		dst := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "redis", RedisAddr: mr.Addr(), Prefix: "export_"}})
+		imported, err := dst.ImportState(ctx, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, imported)
+
+		assert.True(t, isRevokedForTest(dst, token))
+		assert.Equal(t, time.Unix(1700000000, 0), dst.RevocationEpoch())
+		state, found, err := dst.Store().GetGrace(ctx, "grace-key")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "new-key", state.NewTokenKey)
+		got, found, err := dst.Store().GetSession(ctx, "sess-1")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, sess.Data, got.Data)
+
+		assert.InDelta(t, time.Minute.Seconds(), mr.TTL("export_state:grace:grace-key").Seconds(), 2)
+		assert.InDelta(t, time.Hour.Seconds(), mr.TTL("export_state:session:sess-1").Seconds(), 2)
+		assert.InDelta(t, 3600, mr.TTL("export_blacklist:"+dst.TokenKey(token)).Seconds(), 5)
+	})
+
+	// 测试用例2: 已过期的记录被跳过
+	t.Run("SkipExpired", func(t *testing.T) {
+		dst := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "memory"}})
+		input := `{"kind":"revocation","key":"expired","expires_at":1}
+{"kind":"revocation","key":"forever"}
+`
+		imported, err := dst.ImportState(ctx, strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, imported)
+
+		revoked, _ := dst.Store().IsRevoked(ctx, "expired")
+		assert.False(t, revoked)
+		revoked, _ = dst.Store().IsRevoked(ctx, "forever")
+		assert.True(t, revoked)
+	})
+
+	// 测试用例3: 再次导出的结果与导入内容一致
+	t.Run("RoundTrip", func(t *testing.T) {
+		src := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "memory"}})
+		for _, key := range []string{"b", "a", "c"} {
+			assert.NoError(t, src.Store().Revoke(ctx, key, 0))
+		}
+		var first, second bytes.Buffer
+		_, err := src.ExportState(ctx, &first)
+		assert.NoError(t, err)
+
+		dst := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "memory"}})
+		_, err = dst.ImportState(ctx, bytes.NewReader(first.Bytes()))
+		assert.NoError(t, err)
+		_, err = dst.ExportState(ctx, &second)
+		assert.NoError(t, err)
+		assert.Equal(t, first.String(), second.String())
+
+		var rec StateRecord
+		assert.NoError(t, json.Unmarshal(bytes.SplitN(first.Bytes(), []byte("\n"), 2)[0], &rec))
+		assert.Equal(t, StateRecord{Kind: StateRevocation, Key: "a"}, rec, "同类记录按键排序")
+	})
+
+	// 测试用例4: 无法识别的记录返回错误并指出位置
+	t.Run("InvalidRecord", func(t *testing.T) {
+		dst := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "memory"}})
+		input := `{"kind":"revocation","key":"a"}
+{"kind":"unknown"}
+`
+		imported, err := dst.ImportState(ctx, strings.NewReader(input))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "第2条记录")
+		assert.Equal(t, 1, imported)
+
+		_, err = dst.ImportState(ctx, strings.NewReader(`{"kind":"session"}`))
+		assert.Error(t, err)
+		_, err = dst.ImportState(ctx, strings.NewReader(`not json`))
+		assert.Error(t, err)
+	})
+
+	// 测试用例5: 存储不支持枚举时返回ErrExportUnsupported
+	t.Run("Unsupported", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: 3600, Cache: CacheConfig{Type: "memory"}})
+		handler.store = &lookupCounter{Store: handler.store}
+		_, err := handler.ExportState(ctx, &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrExportUnsupported)
+	})
+}
@@ -0,0 +1,527 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 16:05:36
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 16:05:36
+ * Description: Redis不可用时的降级存储
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultRetryInterval 后台重连Redis的默认间隔
+const defaultRetryInterval = 5 * time.Second
+
+// failoverStore Redis启动失败时使用的降级存储
+// 降级期间读写内存缓存并记录撤销、宽限期状态与会话的写入，重连成功后补写到Redis并切换；
+// Token元数据只是签名验证的缓存，不做补写
+type failoverStore struct {
+	cfg CacheConfig
+
+	mu       sync.RWMutex
+	current  Store
+	degraded bool
+	lastErr  error
+	since    time.Time
+	retries  uint64
+	pending  map[string]pendingWrite // 降级期间的写入，按类型与键去重，只保留最后一次
+	seq      uint64
+	subs     map[uint64]*failoverSub
+	nextSub  uint64
+
+	quit      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newFailoverStore(cfg CacheConfig, cause error) (*failoverStore, error) {
+	mem, err := newLocalStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &failoverStore{
+		cfg:      cfg,
+		current:  mem,
+		degraded: true,
+		lastErr:  cause,
+		since:    time.Now(),
+		pending:  make(map[string]pendingWrite),
+		subs:     make(map[uint64]*failoverSub),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if cfg.FailurePolicy == FailureRetry {
+		go s.retry()
+	} else {
+		close(s.done)
+	}
+	return s, nil
+}
+
+// 降级期间需要补写的记录类型
+const (
+	pendingRevocation = "revocation"
+	pendingGrace      = "grace"
+	pendingSession    = "session"
+)
+
+// pendingWrite 降级期间的一次写入
+type pendingWrite struct {
+	kind      string
+	key       string
+	seq       uint64    // 写入序号，补写后同一键再次写入时需要重新补写
+	expiresAt time.Time // 零值表示不过期
+	deleted   bool
+	grace     GraceState
+	session   Session
+}
+
+// record 记录降级期间的写入，调用方需持有写锁
+func (s *failoverStore) record(w pendingWrite, ttl time.Duration) {
+	if !s.degraded {
+		return
+	}
+	if ttl > 0 {
+		w.expiresAt = time.Now().Add(ttl)
+	}
+	s.seq++
+	w.seq = s.seq
+	s.pending[w.kind+":"+w.key] = w
+}
+
+// failoverSub 一个撤销事件订阅，切换后在Redis上重新订阅
+type failoverSub struct {
+	handle func(ev RevocationEvent)
+	stop   func() // 当前存储上的订阅，未订阅时为nil
+}
+
+// retry 定期重连Redis，成功后退出
+func (s *failoverStore) retry() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.retryInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.reconnect() {
+				return
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// reconnect 尝试连接Redis，成功时补写降级期间的撤销、宽限期状态与会话并切换
+// 补写与订阅在锁外进行，不阻塞降级期间的读写；确认没有新的待补写数据后才在锁内切换
+func (s *failoverStore) reconnect() bool {
+	s.mu.Lock()
+	s.retries++
+	s.mu.Unlock()
+
+	next, err := newRedisStore(s.cfg)
+	if err != nil {
+		s.fail(err)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.retryInterval())
+	defer cancel()
+
+	p := &promotion{next: next, replayed: make(map[string]uint64), subs: make(map[uint64]func())}
+	for {
+		s.mu.Lock()
+		batch := s.unreplayed(ctx, p)
+		if batch == nil {
+			old := s.current
+			var oldStops []func()
+			var handles []func(ev RevocationEvent)
+			for id, sub := range s.subs {
+				if sub.stop != nil {
+					oldStops = append(oldStops, sub.stop)
+				}
+				sub.stop = p.subs[id]
+				handles = append(handles, sub.handle)
+			}
+			s.current = next
+			s.degraded = false
+			s.lastErr = nil
+			s.since = time.Now()
+			s.pending = nil
+			s.mu.Unlock()
+
+			for _, stop := range oldStops {
+				stop()
+			}
+			_ = old.Close()
+			// 降级期间其他实例写入Redis的撤销未经过本实例的订阅，通知订阅方立即校准
+			for _, handle := range handles {
+				handle(RevocationEvent{Type: "resync"})
+			}
+			s.notify()
+			return true
+		}
+		s.mu.Unlock()
+
+		if err := p.replay(ctx, batch); err != nil {
+			for _, stop := range p.subs {
+				stop()
+			}
+			next.Close()
+			s.fail(err)
+			return false
+		}
+	}
+}
+
+// retryInterval 重连间隔，同时作为一次补写的时限
+func (s *failoverStore) retryInterval() time.Duration {
+	if s.cfg.RetryInterval > 0 {
+		return time.Duration(s.cfg.RetryInterval) * time.Second
+	}
+	return defaultRetryInterval
+}
+
+// fail 记录重连失败的原因
+func (s *failoverStore) fail(err error) {
+	s.mu.Lock()
+	s.lastErr = err
+	s.mu.Unlock()
+}
+
+// notify 在锁外回调当前状态
+func (s *failoverStore) notify() {
+	if s.cfg.OnFailover != nil {
+		s.cfg.OnFailover(s.status())
+	}
+}
+
+// promotion 切回Redis的进度
+type promotion struct {
+	next     *redisStore
+	epoch    int64             // 已写入Redis的全局撤销时间点
+	replayed map[string]uint64 // 已写入Redis的记录及其写入序号
+	subs     map[uint64]func() // Redis上的撤销事件订阅
+}
+
+// promotionBatch 一轮待补写的数据
+type promotionBatch struct {
+	epoch     int64
+	writes    []pendingWrite
+	subscribe map[uint64]func(ev RevocationEvent)
+}
+
+// unreplayed 返回尚未补写的全局撤销时间点、写入记录与订阅回调，全部补写完成时返回nil，调用方需持有写锁
+// 订阅已被取消时停止其在Redis上的订阅
+func (s *failoverStore) unreplayed(ctx context.Context, p *promotion) *promotionBatch {
+	batch := &promotionBatch{subscribe: make(map[uint64]func(ev RevocationEvent))}
+	pending := false
+	if epoch, err := s.current.GetEpoch(ctx); err == nil && epoch > p.epoch {
+		batch.epoch = epoch
+		pending = true
+	}
+	for id, w := range s.pending {
+		if p.replayed[id] != w.seq {
+			batch.writes = append(batch.writes, w)
+			pending = true
+		}
+	}
+	for id, sub := range s.subs {
+		if _, ok := p.subs[id]; !ok {
+			batch.subscribe[id] = sub.handle
+			pending = true
+		}
+	}
+	for id, stop := range p.subs {
+		if _, ok := s.subs[id]; !ok {
+			stop()
+			delete(p.subs, id)
+		}
+	}
+	if !pending {
+		return nil
+	}
+	return batch
+}
+
+// replay 将一轮数据写入Redis并迁移撤销事件订阅
+func (p *promotion) replay(ctx context.Context, batch *promotionBatch) error {
+	if batch.epoch > 0 {
+		if _, err := p.next.RaiseEpoch(ctx, batch.epoch); err != nil {
+			return err
+		}
+		p.epoch = batch.epoch
+	}
+
+	for _, w := range batch.writes {
+		if err := p.write(ctx, w); err != nil {
+			return err
+		}
+		p.replayed[w.kind+":"+w.key] = w.seq
+	}
+
+	// 订阅失败时仍依赖定期全量校准，不再重试
+	for id, handle := range batch.subscribe {
+		stop, err := p.next.SubscribeRevocations(handle)
+		if err != nil {
+			stop = func() {}
+		}
+		p.subs[id] = stop
+	}
+	return nil
+}
+
+// write 补写一条记录，已过期的记录直接跳过
+// 宽限期状态只在Redis中不存在时写入，其他实例在降级期间已为同一Token续期时以Redis为准
+func (p *promotion) write(ctx context.Context, w pendingWrite) error {
+	var ttl time.Duration
+	if !w.expiresAt.IsZero() {
+		if ttl = time.Until(w.expiresAt); ttl <= 0 && !w.deleted {
+			return nil
+		}
+	}
+
+	var err error
+	switch {
+	case w.kind == pendingRevocation:
+		err = p.next.Revoke(ctx, w.key, ttl)
+	case w.kind == pendingGrace && w.deleted:
+		err = p.next.DeleteGrace(ctx, w.key)
+	case w.kind == pendingGrace:
+		_, _, err = p.next.PutGrace(ctx, w.key, w.grace, ttl)
+	case w.kind == pendingSession && w.deleted:
+		err = p.next.DeleteSession(ctx, w.key)
+	case w.kind == pendingSession:
+		err = p.next.SetSession(ctx, w.session, ttl)
+	}
+	if err != nil {
+		return fmt.Errorf("补写%s记录失败: %v", w.kind, err)
+	}
+	return nil
+}
+
+func (s *failoverStore) status() StoreStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := StoreStatus{
+		Backend:  "redis",
+		Policy:   s.cfg.FailurePolicy,
+		Degraded: s.degraded,
+		Since:    s.since,
+		Retries:  s.retries,
+	}
+	if s.degraded {
+		status.Backend = "memory"
+	}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	return status
+}
+
+func (s *failoverStore) memoryStats() MemoryStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if b, ok := s.current.(*boundedStore); ok {
+		return b.Stats()
+	}
+	return MemoryStats{}
+}
+
+func (s *failoverStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.SetToken(ctx, key, rec, ttl)
+}
+
+func (s *failoverStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.GetToken(ctx, key)
+}
+
+func (s *failoverStore) DeleteToken(ctx context.Context, key string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.DeleteToken(ctx, key)
+}
+
+func (s *failoverStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.current.Revoke(ctx, key, ttl); err != nil {
+		return err
+	}
+	s.record(pendingWrite{kind: pendingRevocation, key: key}, ttl)
+	return nil
+}
+
+func (s *failoverStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.IsRevoked(ctx, key)
+}
+
+func (s *failoverStore) GetEpoch(ctx context.Context) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.GetEpoch(ctx)
+}
+
+func (s *failoverStore) RaiseEpoch(ctx context.Context, epoch int64) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.RaiseEpoch(ctx, epoch)
+}
+
+func (s *failoverStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actual, stored, err := s.current.PutGrace(ctx, key, state, ttl)
+	if err == nil && stored {
+		s.record(pendingWrite{kind: pendingGrace, key: key, grace: state}, ttl)
+	}
+	return actual, stored, err
+}
+
+func (s *failoverStore) GetGrace(ctx context.Context, key string) (GraceState, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.GetGrace(ctx, key)
+}
+
+func (s *failoverStore) DeleteGrace(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.current.DeleteGrace(ctx, key); err != nil {
+		return err
+	}
+	s.record(pendingWrite{kind: pendingGrace, key: key, deleted: true}, 0)
+	return nil
+}
+
+func (s *failoverStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.current.SetSession(ctx, sess, ttl); err != nil {
+		return err
+	}
+	// 会话数据由调用方持有，补写时使用副本
+	if sess.Data != nil {
+		data := make(map[string]string, len(sess.Data))
+		for k, v := range sess.Data {
+			data[k] = v
+		}
+		sess.Data = data
+	}
+	s.record(pendingWrite{kind: pendingSession, key: sess.ID, session: sess}, ttl)
+	return nil
+}
+
+func (s *failoverStore) GetSession(ctx context.Context, id string) (Session, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.GetSession(ctx, id)
+}
+
+func (s *failoverStore) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.current.DeleteSession(ctx, id); err != nil {
+		return err
+	}
+	s.record(pendingWrite{kind: pendingSession, key: id, deleted: true}, 0)
+	return nil
+}
+
+// Revocations 枚举当前生效存储的撤销记录，降级期间只包含本实例的撤销
+func (s *failoverStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if lister, ok := s.current.(RevocationLister); ok {
+		return lister.Revocations(ctx)
+	}
+	return nil, nil
+}
+
+// ExportState 导出当前生效的存储，降级期间只包含回退后写入的记录
+func (s *failoverStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if exporter, ok := s.current.(StateExporter); ok {
+		return exporter.ExportState(ctx)
+	}
+	return nil, ErrExportUnsupported
+}
+
+// PublishRevocation 降级期间没有其他实例可通知，直接忽略
+func (s *failoverStore) PublishRevocation(ctx context.Context, ev RevocationEvent) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if n, ok := s.current.(RevocationNotifier); ok {
+		return n.PublishRevocation(ctx, ev)
+	}
+	return nil
+}
+
+// SubscribeRevocations 记录回调，切换回Redis后自动订阅；多个订阅者互不影响
+func (s *failoverStore) SubscribeRevocations(handle func(ev RevocationEvent)) (func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := &failoverSub{handle: handle}
+	if n, ok := s.current.(RevocationNotifier); ok {
+		stop, err := n.SubscribeRevocations(handle)
+		if err != nil {
+			return nil, err
+		}
+		sub.stop = stop
+	}
+	s.nextSub++
+	id := s.nextSub
+	s.subs[id] = sub
+
+	stop := func() {
+		s.mu.Lock()
+		sub, ok := s.subs[id]
+		delete(s.subs, id)
+		s.mu.Unlock()
+		if ok && sub.stop != nil {
+			sub.stop()
+		}
+	}
+	return stop, nil
+}
+
+func (s *failoverStore) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		<-s.done
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for id, sub := range s.subs {
+			if sub.stop != nil {
+				sub.stop()
+			}
+			delete(s.subs, id)
+		}
+		err = s.current.Close()
+	})
+	return err
+}
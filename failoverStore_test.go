@@ -0,0 +1,211 @@
+package gosjwt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stoppedRedis 启动后立即停止的Redis，返回其地址供后续重启
+func stoppedRedis(t *testing.T) (*miniredis.Miniredis, string) {
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	return mr, addr
+}
+
+func TestFailurePolicy(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: Redis正常时不降级
+	t.Run("Healthy", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.Cache.FailurePolicy = FailureFailClosed
+		handler := newTestHandler(t, config)
+
+		status := handler.StoreStatus()
+		assert.Equal(t, "redis", status.Backend)
+		assert.False(t, status.Degraded)
+	})
+
+	// 测试用例2: fail策略下创建处理器失败
+	t.Run("FailClosed", func(t *testing.T) {
+		_, addr := stoppedRedis(t)
+		config := newRedisTestConfig(addr)
+		config.Cache.FailurePolicy = FailureFailClosed
+		handler, err := NewJwtHandler(config)
+		assert.Error(t, err)
+		assert.Nil(t, handler)
+	})
+
+	// 测试用例3: 默认策略回退到内存缓存，状态接口返回503
+	t.Run("Fallback", func(t *testing.T) {
+		_, addr := stoppedRedis(t)
+		config := newRedisTestConfig(addr)
+		var events []StoreStatus
+		config.Cache.OnFailover = func(status StoreStatus) { events = append(events, status) }
+		handler := newTestHandler(t, config)
+		if assert.Len(t, events, 1) {
+			assert.True(t, events[0].Degraded)
+			assert.NotEmpty(t, events[0].LastError)
+		}
+
+		status := handler.StoreStatus()
+		assert.Equal(t, "memory", status.Backend)
+		assert.Equal(t, FailureFallback, status.Policy)
+		assert.True(t, status.Degraded)
+		assert.NotEmpty(t, status.LastError)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.GET("/status", handler.StatusHandler())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), `"degraded":true`)
+
+		// 降级期间仍可签发与撤销
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+	})
+
+	// 测试用例4: 未知策略
+	t.Run("UnknownPolicy", func(t *testing.T) {
+		config := newRedisTestConfig("127.0.0.1:0")
+		config.Cache.FailurePolicy = "ignore"
+		_, err := NewJwtHandler(config)
+		assert.Error(t, err)
+	})
+
+	// 测试用例5: retry策略在Redis恢复后切回，并补写降级期间的撤销
+	t.Run("RetryReconnect", func(t *testing.T) {
+		mr, addr := stoppedRedis(t)
+		config := newRedisTestConfig(addr)
+		config.Cache.FailurePolicy = FailureRetry
+		config.Cache.RetryInterval = 1
+		events := make(chan StoreStatus, 2)
+		config.Cache.OnFailover = func(status StoreStatus) { events <- status }
+		handler := newTestHandler(t, config)
+		assert.True(t, handler.StoreStatus().Degraded)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		epoch := time.Now().Add(-time.Hour)
+		assert.NoError(t, handler.RevokeIssuedBefore(epoch))
+
+		assert.NoError(t, mr.Restart())
+		assert.Eventually(t, func() bool {
+			return !handler.StoreStatus().Degraded
+		}, 3*time.Second, 50*time.Millisecond)
+
+		status := handler.StoreStatus()
+		assert.Equal(t, "redis", status.Backend)
+		assert.Empty(t, status.LastError)
+		assert.True(t, (<-events).Degraded)
+		assert.False(t, (<-events).Degraded, "切回Redis时回调")
+		assert.GreaterOrEqual(t, status.Retries, uint64(1))
+
+		assert.True(t, mr.Exists("test_blacklist:"+handler.TokenKey(token)))
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+		stored, err := handler.store.GetEpoch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, epochSeconds(epoch), stored)
+	})
+
+	// 测试用例6: 本地一级缓存与本地撤销集合的订阅在切回Redis后都重新订阅，并立即校准
+	t.Run("RetryResubscribesAll", func(t *testing.T) {
+		mr, addr := stoppedRedis(t)
+		config := newRedisTestConfig(addr)
+		config.Cache.FailurePolicy = FailureRetry
+		config.Cache.RetryInterval = 1
+		config.Cache.LocalCacheTTL = 60000
+		config.LocalRevocation = true
+		handler := newTestHandler(t, config)
+
+		// 降级期间其他实例写入Redis的撤销记录在切回后立即校准到本地撤销集合
+		early, err := handler.ReleaseToken(2)
+		assert.NoError(t, err)
+		earlyKey := "test_blacklist:" + handler.TokenKey(early)
+		assert.NoError(t, mr.Set(earlyKey, "true"))
+		mr.SetTTL(earlyKey, time.Minute)
+
+		assert.NoError(t, mr.Restart())
+		assert.Eventually(t, func() bool {
+			return !handler.StoreStatus().Degraded
+		}, 3*time.Second, 50*time.Millisecond)
+		assert.Eventually(t, func() bool {
+			return handler.revoked.Contains(handler.TokenKey(early))
+		}, time.Second, 10*time.Millisecond, "切回Redis后未立即校准")
+
+		otherConfig := newRedisTestConfig(addr)
+		otherConfig.Cache.FailurePolicy = FailureFailClosed
+		otherConfig.LocalRevocation = true
+		other := newTestHandler(t, otherConfig)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		key := handler.TokenKey(token)
+		revoked, err := handler.store.IsRevoked(ctx, key)
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+
+		// 其他实例的撤销同时清除本地一级缓存并写入本地撤销集合
+		assert.NoError(t, other.RevokeToken(token))
+		assert.Eventually(t, func() bool {
+			revoked, err := handler.store.IsRevoked(ctx, key)
+			return err == nil && revoked
+		}, time.Second, 10*time.Millisecond, "本地一级缓存未收到撤销事件")
+		assert.Eventually(t, func() bool {
+			return handler.revoked.Contains(key)
+		}, time.Second, 10*time.Millisecond, "本地撤销集合未收到撤销事件")
+	})
+
+	// 测试用例7: 降级期间写入的会话与宽限期状态在切回Redis后保留
+	t.Run("RetryReplaysSessionsAndGrace", func(t *testing.T) {
+		mr, addr := stoppedRedis(t)
+		config := newRedisTestConfig(addr)
+		config.Cache.FailurePolicy = FailureRetry
+		config.Cache.RetryInterval = 1
+		handler := newTestHandler(t, config)
+
+		deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+		sess := Session{ID: "sess-1", UserId: 7, Data: map[string]string{"role": "admin"}, ExpiresAt: deadline}
+		assert.NoError(t, handler.store.SetSession(ctx, sess, time.Minute))
+		assert.NoError(t, handler.store.SetSession(ctx, Session{ID: "sess-2", UserId: 8}, time.Minute))
+		assert.NoError(t, handler.store.DeleteSession(ctx, "sess-2"))
+		renewed := GraceState{Deadline: deadline, NewTokenKey: "renewed"}
+		_, stored, err := handler.store.PutGrace(ctx, "old-token", renewed, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+
+		assert.NoError(t, mr.Restart())
+		assert.Eventually(t, func() bool {
+			return !handler.StoreStatus().Degraded
+		}, 3*time.Second, 50*time.Millisecond)
+
+		got, found, err := handler.store.GetSession(ctx, "sess-1")
+		assert.NoError(t, err)
+		assert.True(t, found, "降级期间创建的会话应补写到Redis")
+		assert.Equal(t, sess.Data, got.Data)
+		_, found, err = handler.store.GetSession(ctx, "sess-2")
+		assert.NoError(t, err)
+		assert.False(t, found, "降级期间删除的会话不应出现")
+
+		// 已续期的旧Token不能再次续期
+		actual, stored, err := handler.store.PutGrace(ctx, "old-token", GraceState{Deadline: deadline, NewTokenKey: "again"}, time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, stored, "降级期间的宽限期状态应补写到Redis")
+		assert.Equal(t, "renewed", actual.NewTokenKey)
+	})
+}
@@ -0,0 +1,69 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 23:44:52
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 23:44:52
+ * Description: Fiber认证中间件
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// FiberMiddleware 创建Fiber认证中间件
+// 认证通过后通过c.Locals("userID")读取用户ID，c.UserContext()中同时写入Claims；失败时的状态码与响应体与GinMiddleware一致
+func (j *JwtHandler) FiberMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		res := j.authenticate(ctx, fiberTokenRequest{c: c, maxForm: j.maxFormSize()})
+		if res.status != 0 {
+			c.Set(fiber.HeaderContentType, jsonContentType)
+			return c.Status(res.status).Send(errorBody(res.message))
+		}
+		for name, values := range j.renewalHeaders(res) {
+			for _, v := range values {
+				c.Response().Header.Add(name, v)
+			}
+		}
+		c.SetUserContext(withClaims(ctx, res.claims))
+		c.Locals(UserIdContextKey, res.claims.UserId)
+		return c.Next()
+	}
+}
+
+// fiberTokenRequest 基于Fiber请求读取Token
+// fasthttp会复用请求缓冲区，Token可能被宽限期调度等异步流程持有，返回值均需复制
+type fiberTokenRequest struct {
+	c       *fiber.Ctx
+	maxForm int64 // 表单来源允许解析的请求体上限
+}
+
+func (f fiberTokenRequest) tokenValue(typ TokenLookupType, name string) string {
+	switch typ {
+	case LookupHeader:
+		return utils.CopyString(f.c.Get(name))
+	case LookupQuery:
+		return string(f.c.Request().URI().QueryArgs().Peek(name))
+	case LookupCookie:
+		return utils.CopyString(f.c.Cookies(name))
+	case LookupForm:
+		// 只读取请求体中的字段，查询参数需配置LookupQuery；超过上限的请求体不解析
+		if int64(len(f.c.Request().Body())) > f.maxForm {
+			return ""
+		}
+		if value := f.c.Request().PostArgs().Peek(name); len(value) > 0 {
+			return string(value)
+		}
+		if form, err := f.c.MultipartForm(); err == nil && len(form.Value[name]) > 0 {
+			return form.Value[name][0]
+		}
+	}
+	return ""
+}
+
+func (f fiberTokenRequest) requestMethod() string {
+	return f.c.Method()
+}
@@ -0,0 +1,488 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 17:46:22
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 17:46:22
+ * Description: 基于追加日志的单机持久化存储
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFileCompactInterval = 10 * time.Minute
+	fileCompactMinEntries      = 1000 // 日志条数低于该值时不压缩
+
+	fileKindRevocation = "revocation"
+	fileKindGrace      = "grace"
+	fileKindSession    = "session"
+	fileKindEpoch      = "epoch"
+)
+
+// fileLogEntry 追加日志中的一行
+type fileLogEntry struct {
+	Op     string          `json:"op"` // "set" 或 "del"
+	Kind   string          `json:"kind"`
+	Key    string          `json:"key,omitempty"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Expiry int64           `json:"expiry,omitempty"` // Unix毫秒，0表示不过期
+}
+
+// fileRecord 内存中的记录
+type fileRecord struct {
+	value  json.RawMessage
+	expiry int64
+}
+
+func (r fileRecord) expired(now int64) bool {
+	return r.expiry > 0 && r.expiry <= now
+}
+
+// FileStore 单机持久化存储：撤销记录、全局撤销时间点、宽限期状态与会话写入追加日志，重启后重放恢复
+// Token元数据只是缓存，仅保存在内存中
+// 日志条数超过存活记录的两倍时压缩，后台定期压缩并清理过期记录
+// 同一数据文件只能被一个进程打开：类Unix系统上通过数据文件旁的.lock文件加锁，其他系统需由部署保证
+type FileStore struct {
+	path string
+	lock *os.File // 进程锁，关闭时释放
+
+	mu         sync.Mutex
+	file       *os.File
+	records    map[string]map[string]fileRecord // kind -> key -> 记录
+	tokens     map[string]fileRecord
+	appended   int   // 当前日志条数
+	compactErr error // 最近一次自动压缩的错误，下次写入时重试
+
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewFileStore 打开或创建数据文件并重放日志
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("数据文件路径不能为空")
+	}
+	s := &FileStore{
+		path:    path,
+		records: make(map[string]map[string]fileRecord),
+		tokens:  make(map[string]fileRecord),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("锁定数据文件失败: %v", err)
+	}
+	s.lock = lock
+	if err := s.load(); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("加载数据文件失败: %v", err)
+	}
+	// 启动时压缩一次，去除过期记录与崩溃时写入一半的行
+	if err := s.compactLocked(); err != nil {
+		lock.Close()
+		return nil, fmt.Errorf("压缩数据文件失败: %v", err)
+	}
+	go s.compactLoop(defaultFileCompactInterval)
+	return s, nil
+}
+
+// load 重放日志，无法解析的行视为崩溃时未写完的数据并跳过
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry fileLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		s.apply(entry)
+	}
+	return scanner.Err()
+}
+
+func (s *FileStore) apply(entry fileLogEntry) {
+	bucket := s.records[entry.Kind]
+	if bucket == nil {
+		bucket = make(map[string]fileRecord)
+		s.records[entry.Kind] = bucket
+	}
+	switch entry.Op {
+	case "set":
+		bucket[entry.Key] = fileRecord{value: entry.Value, expiry: entry.Expiry}
+	case "del":
+		delete(bucket, entry.Key)
+	}
+}
+
+// write 追加日志并更新内存，sync为true时落盘后返回
+func (s *FileStore) write(entry fileLogEntry, sync bool) error {
+	if s.file == nil {
+		return fmt.Errorf("文件存储已关闭")
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("编码日志失败: %v", err)
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入数据文件失败: %v", err)
+	}
+	if sync {
+		if err := s.file.Sync(); err != nil {
+			return fmt.Errorf("同步数据文件失败: %v", err)
+		}
+	}
+	s.apply(entry)
+	s.appended++
+
+	// 记录已经写入，压缩失败不影响本次结果，只记录错误并在下次写入时重试
+	if s.appended > fileCompactMinEntries && s.appended > 2*s.liveCount() {
+		s.compactErr = s.compactLocked()
+	}
+	return nil
+}
+
+func (s *FileStore) set(kind, key string, v interface{}, ttl time.Duration, sync bool) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("编码存储值失败: %v", err)
+	}
+	return s.write(fileLogEntry{Op: "set", Kind: kind, Key: key, Value: value, Expiry: expiryMillis(ttl)}, sync)
+}
+
+func (s *FileStore) del(kind, key string) error {
+	if _, ok := s.records[kind][key]; !ok {
+		return nil
+	}
+	return s.write(fileLogEntry{Op: "del", Kind: kind, Key: key}, false)
+}
+
+// get 读取未过期的记录并解码到v
+func (s *FileStore) get(kind, key string, v interface{}) (bool, error) {
+	rec, ok := s.records[kind][key]
+	if !ok || rec.expired(time.Now().UnixMilli()) {
+		return false, nil
+	}
+	if err := json.Unmarshal(rec.value, v); err != nil {
+		return false, fmt.Errorf("解码存储值失败: %v", err)
+	}
+	return true, nil
+}
+
+func (s *FileStore) liveCount() int {
+	n := 0
+	for _, bucket := range s.records {
+		n += len(bucket)
+	}
+	return n
+}
+
+// Compact 清理过期记录并以当前状态重写数据文件
+func (s *FileStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("文件存储已关闭")
+	}
+	s.compactErr = s.compactLocked()
+	return s.compactErr
+}
+
+func (s *FileStore) compactLocked() error {
+	now := time.Now().UnixMilli()
+	for key, rec := range s.tokens {
+		if rec.expired(now) {
+			delete(s.tokens, key)
+		}
+	}
+
+	// 新文件以追加方式打开并在重命名后直接作为数据文件，替换完成前旧文件句柄保持可用
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	abort := func(err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	count := 0
+	for kind, bucket := range s.records {
+		for key, rec := range bucket {
+			if rec.expired(now) {
+				delete(bucket, key)
+				continue
+			}
+			line, err := json.Marshal(fileLogEntry{Op: "set", Kind: kind, Key: key, Value: rec.value, Expiry: rec.expiry})
+			if err != nil {
+				return abort(err)
+			}
+			w.Write(append(line, '\n'))
+			count++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return abort(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return abort(err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return abort(err)
+	}
+	// 重命名已生效，目录同步失败只影响断电后的持久性，仍切换到新文件
+	dirErr := syncDir(filepath.Dir(s.path))
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = tmp
+	s.appended = count
+	return dirErr
+}
+
+func (s *FileStore) compactLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_ = s.Compact()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *FileStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[key] = fileRecord{value: value, expiry: expiryMillis(ttl)}
+	return nil
+}
+
+func (s *FileStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	s.mu.Lock()
+	r, ok := s.tokens[key]
+	s.mu.Unlock()
+
+	if !ok || r.expired(time.Now().UnixMilli()) {
+		return TokenRecord{}, false, nil
+	}
+	var rec TokenRecord
+	if err := json.Unmarshal(r.value, &rec); err != nil {
+		return TokenRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (s *FileStore) DeleteToken(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, key)
+	return nil
+}
+
+// Revoke 撤销记录落盘后返回，进程崩溃也不会丢失
+func (s *FileStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(fileKindRevocation, key, true, ttl, true)
+}
+
+func (s *FileStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rec, ok := s.records[fileKindRevocation][key]
+	return ok && !rec.expired(time.Now().UnixMilli()), nil
+}
+
+// Revocations 返回未过期的撤销记录，不过期的记录按默认时长保留
+func (s *FileStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	snapshot := make(map[string]time.Time)
+	for key, rec := range s.records[fileKindRevocation] {
+		switch {
+		case rec.expiry == 0:
+			snapshot[key] = now.Add(defaultRevocationTTL)
+		case !rec.expired(now.UnixMilli()):
+			snapshot[key] = time.UnixMilli(rec.expiry)
+		}
+	}
+	return snapshot, nil
+}
+
+// ExportState 返回未过期的撤销记录、宽限期状态与会话
+func (s *FileStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	var records []StateRecord
+	for _, kind := range []string{fileKindRevocation, fileKindGrace, fileKindSession} {
+		for key, r := range s.records[kind] {
+			if r.expired(now) {
+				continue
+			}
+			rec := StateRecord{Kind: kind, ExpiresAt: r.expiry}
+			var target interface{}
+			switch kind {
+			case fileKindRevocation:
+				rec.Key = key
+			case fileKindGrace:
+				rec.Key, rec.Grace = key, &GraceState{}
+				target = rec.Grace
+			case fileKindSession:
+				rec.Session = &Session{}
+				target = rec.Session
+			}
+			if target != nil {
+				if err := json.Unmarshal(r.value, target); err != nil {
+					return nil, fmt.Errorf("解码存储值失败: %v", err)
+				}
+			}
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func (s *FileStore) GetEpoch(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var epoch int64
+	_, err := s.get(fileKindEpoch, "", &epoch)
+	return epoch, err
+}
+
+func (s *FileStore) RaiseEpoch(ctx context.Context, epoch int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current int64
+	if _, err := s.get(fileKindEpoch, "", &current); err != nil {
+		return 0, err
+	}
+	if current >= epoch {
+		return current, nil
+	}
+	if err := s.set(fileKindEpoch, "", epoch, 0, true); err != nil {
+		return 0, fmt.Errorf("写入全局撤销时间点失败: %v", err)
+	}
+	return epoch, nil
+}
+
+func (s *FileStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var existing GraceState
+	if found, err := s.get(fileKindGrace, key, &existing); err != nil || found {
+		return existing, false, err
+	}
+	if err := s.set(fileKindGrace, key, state, ttl, false); err != nil {
+		return GraceState{}, false, err
+	}
+	return state, true, nil
+}
+
+func (s *FileStore) GetGrace(ctx context.Context, key string) (GraceState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var state GraceState
+	found, err := s.get(fileKindGrace, key, &state)
+	return state, found, err
+}
+
+func (s *FileStore) DeleteGrace(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.del(fileKindGrace, key)
+}
+
+func (s *FileStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	if sess.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(fileKindSession, sess.ID, sess, ttl, false)
+}
+
+func (s *FileStore) GetSession(ctx context.Context, id string) (Session, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sess Session
+	found, err := s.get(fileKindSession, id, &sess)
+	return sess, found, err
+}
+
+func (s *FileStore) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.del(fileKindSession, id)
+}
+
+// status 自动压缩失败时在LastError中报告
+func (s *FileStore) status() StoreStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := StoreStatus{Backend: "file"}
+	if s.compactErr != nil {
+		status.LastError = fmt.Sprintf("压缩数据文件失败: %v", s.compactErr)
+	}
+	return status
+}
+
+// Close 停止后台压缩并关闭数据文件
+func (s *FileStore) Close() error {
+	var err error
+	s.stopOnce.Do(func() {
+		close(s.quit)
+		<-s.done
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.file != nil {
+			err = s.file.Sync()
+			if cerr := s.file.Close(); err == nil {
+				err = cerr
+			}
+			s.file = nil
+		}
+		s.lock.Close()
+	})
+	return err
+}
@@ -0,0 +1,46 @@
+//go:build unix
+
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/20 02:14:51
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/20 02:14:51
+ * Description: 文件存储的进程间互斥与目录同步(类Unix系统)
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+
+package gosjwt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lockFile 以非阻塞方式独占锁定文件，另一进程已持有时返回错误
+// 锁加在单独的锁文件上，数据文件压缩时被替换不影响锁
+func lockFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("数据文件已被其他进程使用")
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// syncDir 同步目录项，确保重命名在断电后仍然有效
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/20 02:14:51
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/20 02:14:51
+ * Description: 文件存储的进程间互斥与目录同步(非Unix系统)
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+
+package gosjwt
+
+import "os"
+
+// lockFile 非Unix系统不加进程锁，需由部署保证同一数据文件只被一个进程打开
+func lockFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+}
+
+// syncDir 非Unix系统无法同步目录，重命名的持久性由文件系统保证
+func syncDir(dir string) error {
+	return nil
+}
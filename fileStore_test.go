@@ -0,0 +1,172 @@
+package gosjwt
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 重启后恢复撤销记录、会话、宽限期状态与全局撤销时间点
+	t.Run("PersistAcrossRestart", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "gosjwt.log")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+
+		assert.NoError(t, store.Revoke(ctx, "token-a", time.Hour))
+		assert.NoError(t, store.Revoke(ctx, "token-b", time.Millisecond))
+		assert.NoError(t, store.SetSession(ctx, Session{ID: "sess-1", UserId: 7}, time.Hour))
+		assert.NoError(t, store.SetSession(ctx, Session{ID: "sess-2", UserId: 8}, time.Hour))
+		assert.NoError(t, store.DeleteSession(ctx, "sess-2"))
+		_, _, err = store.PutGrace(ctx, "token-c", GraceState{Deadline: time.Now().Add(time.Minute)}, time.Hour)
+		assert.NoError(t, err)
+		_, err = store.RaiseEpoch(ctx, 100)
+		assert.NoError(t, err)
+		assert.NoError(t, store.SetToken(ctx, "token-d", TokenRecord{UserId: 1}, time.Hour))
+		assert.NoError(t, store.Close())
+
+		time.Sleep(5 * time.Millisecond)
+		store, err = NewFileStore(path)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		revoked, _ := store.IsRevoked(ctx, "token-a")
+		assert.True(t, revoked)
+		revoked, _ = store.IsRevoked(ctx, "token-b")
+		assert.False(t, revoked)
+
+		sess, found, err := store.GetSession(ctx, "sess-1")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, uint(7), sess.UserId)
+		_, found, _ = store.GetSession(ctx, "sess-2")
+		assert.False(t, found)
+
+		_, found, _ = store.GetGrace(ctx, "token-c")
+		assert.True(t, found)
+		epoch, _ := store.GetEpoch(ctx)
+		assert.Equal(t, int64(100), epoch)
+
+		// Token元数据只是缓存，不持久化
+		_, found, _ = store.GetToken(ctx, "token-d")
+		assert.False(t, found)
+	})
+
+	// 测试用例2: 日志膨胀后自动压缩
+	t.Run("Compaction", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "gosjwt.log")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		for i := 0; i < 3*fileCompactMinEntries; i++ {
+			assert.NoError(t, store.SetSession(ctx, Session{ID: "sess", UserId: uint(i)}, time.Hour))
+		}
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Less(t, bytes.Count(data, []byte("\n")), fileCompactMinEntries+2)
+
+		assert.NoError(t, store.Compact())
+		data, err = os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, bytes.Count(data, []byte("\n")))
+
+		sess, found, _ := store.GetSession(ctx, "sess")
+		assert.True(t, found)
+		assert.Equal(t, uint(3*fileCompactMinEntries-1), sess.UserId)
+	})
+
+	// 测试用例3: 忽略崩溃时写入一半的行
+	t.Run("TruncatedLine", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "gosjwt.log")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Revoke(ctx, "token-a", time.Hour))
+		assert.NoError(t, store.Close())
+
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+		assert.NoError(t, err)
+		_, _ = f.WriteString(`{"op":"set","kind":"revocation","key":"tok`)
+		f.Close()
+
+		store, err = NewFileStore(path)
+		assert.NoError(t, err)
+		defer store.Close()
+		revoked, _ := store.IsRevoked(ctx, "token-a")
+		assert.True(t, revoked)
+	})
+
+	// 测试用例4: 通过缓存配置启用，重启后撤销仍然有效
+	t.Run("HandlerRestart", func(t *testing.T) {
+		config := &Config{
+			SigningKey: []byte("file-store-key"),
+			Expires:    3600,
+			Cache:      CacheConfig{Type: "file", FilePath: filepath.Join(t.TempDir(), "gosjwt.log")},
+		}
+		handler, err := NewJwtHandler(config)
+		assert.NoError(t, err)
+		assert.Equal(t, "file", handler.StoreStatus().Backend)
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		handler.Close()
+
+		handler, err = NewJwtHandler(config)
+		assert.NoError(t, err)
+		defer handler.Close()
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+	})
+
+	// 测试用例5: 未指定路径
+	t.Run("MissingPath", func(t *testing.T) {
+		_, err := NewJwtHandler(&Config{SigningKey: []byte("k"), Cache: CacheConfig{Type: "file"}})
+		assert.Error(t, err)
+	})
+
+	// 测试用例6: 同一数据文件不能被同时打开，关闭后可再次打开
+	t.Run("ExclusiveLock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "gosjwt.log")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+
+		_, err = NewFileStore(path)
+		assert.Error(t, err)
+
+		assert.NoError(t, store.Close())
+		store, err = NewFileStore(path)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Close())
+	})
+
+	// 测试用例7: 自动压缩失败不影响已写入的撤销，错误通过状态报告，旧文件继续追加
+	t.Run("CompactionFailure", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "gosjwt.log")
+		store, err := NewFileStore(path)
+		assert.NoError(t, err)
+		// 临时文件路径被目录占用，压缩无法创建新文件
+		assert.NoError(t, os.Mkdir(path+".tmp", 0700))
+
+		for i := 0; i < 3*fileCompactMinEntries; i++ {
+			assert.NoError(t, store.SetSession(ctx, Session{ID: "sess", UserId: uint(i)}, time.Hour))
+		}
+		assert.NoError(t, store.Revoke(ctx, "token-a", time.Hour))
+		assert.NotEmpty(t, store.status().LastError)
+		assert.Error(t, store.Compact())
+		assert.NoError(t, store.Close())
+
+		assert.NoError(t, os.Remove(path+".tmp"))
+		store, err = NewFileStore(path)
+		assert.NoError(t, err)
+		defer store.Close()
+		revoked, _ := store.IsRevoked(ctx, "token-a")
+		assert.True(t, revoked)
+		assert.Empty(t, store.status().LastError)
+	})
+}
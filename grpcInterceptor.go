@@ -0,0 +1,124 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 22:36:05
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 22:36:05
+ * Description: gRPC服务端认证拦截器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcAuthorizationKey 携带Token的metadata键，gRPC要求小写
+const grpcAuthorizationKey = "authorization"
+
+// UnaryServerInterceptor 创建gRPC一元调用认证拦截器，skipMethods为无需认证的完整方法名（如登录接口）
+// 按Config.TokenLookup中的header来源从metadata读取Token（默认authorization的"Bearer <token>"），认证流程与GinMiddleware一致；
+// 认证通过后Claims写入ctx，宽限期续期的新Token通过响应header的authorization返回
+func (j *JwtHandler) UnaryServerInterceptor(skipMethods ...string) grpc.UnaryServerInterceptor {
+	skip := methodSet(skipMethods)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if skip[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		res := j.authenticate(ctx, incomingTokenRequest(ctx))
+		if res.status != 0 {
+			return nil, grpcStatus(res)
+		}
+		if res.newToken != "" {
+			if err := grpc.SetHeader(ctx, metadata.Pairs(grpcAuthorizationKey, "Bearer "+res.newToken)); err != nil {
+				return nil, status.Error(codes.Internal, "Failed to send new token")
+			}
+		}
+		return handler(withClaims(ctx, res.claims), req)
+	}
+}
+
+// StreamServerInterceptor 创建gRPC流式调用认证拦截器，在建立流时认证一次，行为与UnaryServerInterceptor一致
+func (j *JwtHandler) StreamServerInterceptor(skipMethods ...string) grpc.StreamServerInterceptor {
+	skip := methodSet(skipMethods)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if skip[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx := ss.Context()
+		res := j.authenticate(ctx, incomingTokenRequest(ctx))
+		if res.status != 0 {
+			return grpcStatus(res)
+		}
+		if res.newToken != "" {
+			if err := ss.SetHeader(metadata.Pairs(grpcAuthorizationKey, "Bearer "+res.newToken)); err != nil {
+				return status.Error(codes.Internal, "Failed to send new token")
+			}
+		}
+		return handler(srv, &claimsServerStream{ServerStream: ss, ctx: withClaims(ctx, res.claims)})
+	}
+}
+
+// claimsServerStream 替换流的ctx以携带Claims
+type claimsServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *claimsServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// metadataTokenRequest 基于gRPC metadata读取Token，只支持LookupHeader来源，键名不区分大小写
+type metadataTokenRequest metadata.MD
+
+func incomingTokenRequest(ctx context.Context) metadataTokenRequest {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return metadataTokenRequest(md)
+}
+
+// tokenValue 多个值时取第一个
+func (m metadataTokenRequest) tokenValue(typ TokenLookupType, name string) string {
+	if typ != LookupHeader {
+		return ""
+	}
+	if values := metadata.MD(m).Get(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (m metadataTokenRequest) requestMethod() string {
+	return ""
+}
+
+// grpcStatus 将认证失败映射为gRPC状态：撤销为PermissionDenied，其他凭证问题为Unauthenticated，
+// 存储超时或不可用为Unavailable，请求取消为Canceled；消息与HTTP响应的error字段一致
+func grpcStatus(res authResult) error {
+	code := codes.Unauthenticated
+	switch {
+	case errors.Is(res.err, ErrTokenRevoked):
+		code = codes.PermissionDenied
+	case errors.Is(res.err, context.Canceled):
+		code = codes.Canceled
+	case res.status == http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case res.status == http.StatusInternalServerError:
+		code = codes.Internal
+	}
+	return status.Error(code, res.message)
+}
+
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[m] = true
+	}
+	return set
+}
@@ -0,0 +1,205 @@
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// claimsHealthServer 通过响应header返回ctx中的用户ID
+type claimsHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+}
+
+func userIdHeader(ctx context.Context) metadata.MD {
+	if userId, ok := UserIdFromContext(ctx); ok {
+		return metadata.Pairs("user-id", strconv.FormatUint(uint64(userId), 10))
+	}
+	return metadata.MD{}
+}
+
+func (claimsHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if err := grpc.SetHeader(ctx, userIdHeader(ctx)); err != nil {
+		return nil, err
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (claimsHealthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if err := stream.SetHeader(userIdHeader(stream.Context())); err != nil {
+		return err
+	}
+	return stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+}
+
+// startGrpcTestServer 在bufconn上启动挂载拦截器的服务
+func startGrpcTestServer(t *testing.T, handler *JwtHandler, skipMethods ...string) *bufconn.Listener {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(handler.UnaryServerInterceptor(skipMethods...)),
+		grpc.StreamInterceptor(handler.StreamServerInterceptor(skipMethods...)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, claimsHealthServer{})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+// dialGrpcTest 通过bufconn连接测试服务
+func dialGrpcTest(t *testing.T, lis *bufconn.Listener, opts ...grpc.DialOption) grpc_health_v1.HealthClient {
+	opts = append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)
+	conn, err := grpc.DialContext(context.Background(), "bufnet", opts...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+// newGrpcTestClient 启动测试服务并返回客户端
+func newGrpcTestClient(t *testing.T, handler *JwtHandler, skipMethods ...string) grpc_health_v1.HealthClient {
+	return dialGrpcTest(t, startGrpcTestServer(t, handler, skipMethods...))
+}
+
+// withBearer 在出站metadata中携带Token
+func withBearer(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestGrpcInterceptor(t *testing.T) {
+	req := &grpc_health_v1.HealthCheckRequest{}
+
+	// 测试用例1: 有效Token通过认证，Claims写入ctx
+	t.Run("Valid", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: 3600})
+		client := newGrpcTestClient(t, handler)
+		token, err := handler.ReleaseToken(7)
+		assert.NoError(t, err)
+
+		var header metadata.MD
+		_, err = client.Check(withBearer(token), req, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"7"}, header.Get("user-id"))
+		assert.Empty(t, header.Get("authorization"))
+	})
+
+	// 测试用例2: 缺少或无效的Token返回Unauthenticated
+	t.Run("Unauthenticated", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: 3600})
+		client := newGrpcTestClient(t, handler)
+
+		_, err := client.Check(context.Background(), req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "Authorization header required", status.Convert(err).Message())
+
+		_, err = client.Check(withBearer("invalid.token.string"), req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "Invalid token", status.Convert(err).Message())
+	})
+
+	// 测试用例3: 已撤销的Token返回PermissionDenied
+	t.Run("Revoked", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: 3600})
+		client := newGrpcTestClient(t, handler)
+		token, err := handler.ReleaseToken(7)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+
+		_, err = client.Check(withBearer(token), req)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		assert.Equal(t, "Token revoked", status.Convert(err).Message())
+	})
+
+	// 测试用例4: 宽限期续期的新Token通过响应header返回
+	t.Run("GraceRenewal", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: -1, GracePeriod: 60})
+		client := newGrpcTestClient(t, handler)
+		token, err := handler.ReleaseToken(7)
+		assert.NoError(t, err)
+
+		var header metadata.MD
+		_, err = client.Check(withBearer(token), req, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"7"}, header.Get("user-id"))
+		renewed := header.Get("authorization")
+		if assert.Len(t, renewed, 1) {
+			assert.Contains(t, renewed[0], "Bearer ")
+		}
+
+		// 宽限期内再次使用旧Token仍可通过，但不会重复签发
+		header = nil
+		_, err = client.Check(withBearer(token), req, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Empty(t, header.Get("authorization"))
+	})
+
+	// 测试用例5: 流式调用同样认证并返回续期Token
+	t.Run("Stream", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: -1, GracePeriod: 60})
+		client := newGrpcTestClient(t, handler)
+		token, err := handler.ReleaseToken(8)
+		assert.NoError(t, err)
+
+		stream, err := client.Watch(withBearer(token), req)
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.NoError(t, err)
+		header, err := stream.Header()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"8"}, header.Get("user-id"))
+		assert.Len(t, header.Get("authorization"), 1)
+
+		stream, err = client.Watch(context.Background(), req)
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	// 测试用例6: 跳过的方法无需Token
+	t.Run("SkipMethods", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: 3600})
+		client := newGrpcTestClient(t, handler, "/grpc.health.v1.Health/Check")
+
+		var header metadata.MD
+		_, err := client.Check(context.Background(), req, grpc.Header(&header))
+		assert.NoError(t, err)
+		assert.Empty(t, header.Get("user-id"))
+
+		stream, err := client.Watch(context.Background(), req)
+		assert.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), "未跳过的方法仍需认证")
+	})
+
+	// 测试用例7: 存储不可用返回Unavailable
+	t.Run("StoreUnavailable", func(t *testing.T) {
+		store, err := NewStore(CacheConfig{Type: "memory"})
+		assert.NoError(t, err)
+		handler := newTestHandler(t, &Config{
+			Expires: 3600,
+			Store:   &slowStore{Store: store, err: errors.New("connection refused")},
+			Breaker: BreakerConfig{RevocationPolicy: RevocationFailClosed},
+		})
+		client := newGrpcTestClient(t, handler)
+		token, err := handler.ReleaseToken(7)
+		assert.NoError(t, err)
+
+		_, err = client.Check(withBearer(token), req)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, "Token store unavailable", status.Convert(err).Message())
+	})
+}
@@ -0,0 +1,79 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 22:10:36
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 22:10:36
+ * Description: 标准库net/http认证中间件
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// claimsContextKey 请求ctx中保存Claims的键
+type claimsContextKey struct{}
+
+// HttpMiddleware 创建net/http认证中间件，可用于标准库、chi等兼容http.Handler的路由
+// 认证通过后Claims写入请求ctx，使用ClaimsFromContext或UserIdFromContext读取；失败时的状态码与响应体与GinMiddleware一致
+func (j *JwtHandler) HttpMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := j.authenticate(r.Context(), httpTokenRequest{r: r, maxForm: j.maxFormSize()})
+			if res.status != 0 {
+				writeJSONError(w, res.status, res.message)
+				return
+			}
+			writeRenewal(w.Header(), j.renewalHeaders(res))
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), res.claims)))
+		})
+	}
+}
+
+// writeRenewal 写入续期响应头
+func writeRenewal(dst, renewal http.Header) {
+	for name, values := range renewal {
+		for _, v := range values {
+			dst.Add(name, v)
+		}
+	}
+}
+
+// ClaimsFromContext 读取认证中间件写入的Claims
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// UserIdFromContext 读取认证中间件写入的用户ID
+func UserIdFromContext(ctx context.Context) (uint, bool) {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	return claims.UserId, true
+}
+
+// withClaims 将Claims写入ctx
+func withClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// jsonContentType 错误响应的Content-Type，与gin一致
+const jsonContentType = "application/json; charset=utf-8"
+
+// writeJSONError 以与gin.AbortWithStatusJSON相同的格式返回错误
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(errorBody(message))
+}
+
+// errorBody 认证失败的响应体，各框架适配器共用以保证逐字节一致
+func errorBody(message string) []byte {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	return body
+}
@@ -0,0 +1,221 @@
+package gosjwt
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// 挂载net/http中间件的简单路由，与setupGraceRouter返回相同的内容
+func setupHttpRouter(handler *JwtHandler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/grace", func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := UserIdFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"userID":` + strconv.FormatUint(uint64(userID), 10) + `}`))
+	})
+	return handler.HttpMiddleware()(mux)
+}
+
+// 挂载Echo中间件的简单路由
+func setupEchoRouter(handler *JwtHandler) http.Handler {
+	e := echo.New()
+	e.Use(handler.EchoMiddleware())
+	e.Any("/grace", func(c echo.Context) error {
+		if _, ok := ClaimsFromContext(c.Request().Context()); !ok {
+			return c.NoContent(http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"userID": c.Get(UserIdContextKey)})
+	})
+	return e
+}
+
+// 挂载Fiber中间件的简单路由，通过app.Test转换为http.Handler
+func setupFiberRouter(handler *JwtHandler) http.Handler {
+	// 默认4KB的读缓冲会在中间件之前拒绝超长请求头，放大以便测试MaxTokenSize
+	app := fiber.New(fiber.Config{ReadBufferSize: 4 * defaultMaxTokenSize})
+	app.Use(handler.FiberMiddleware())
+	app.All("/grace", func(c *fiber.Ctx) error {
+		if _, ok := ClaimsFromContext(c.UserContext()); !ok {
+			return c.SendStatus(http.StatusInternalServerError)
+		}
+		return c.JSON(fiber.Map{"userID": c.Locals(UserIdContextKey)})
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, err := app.Test(r, -1)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+}
+
+// routers 挂载各框架中间件的路由，同一场景在所有框架上执行
+var routers = []struct {
+	name  string
+	setup func(h *JwtHandler) http.Handler
+}{
+	{"Gin", func(h *JwtHandler) http.Handler { return setupGraceRouter(h) }},
+	{"Http", setupHttpRouter},
+	{"Echo", setupEchoRouter},
+	{"Fiber", setupFiberRouter},
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	// 同一场景分别经过Gin、net/http、Echo与Fiber中间件，状态码、错误响应与续期响应头一致
+	scenarios := []struct {
+		name   string
+		config func() *Config
+		header func(t *testing.T, h *JwtHandler) string
+		status int
+		body   string
+	}{
+		{
+			name:   "Valid",
+			config: func() *Config { return &Config{Expires: 3600} },
+			header: func(t *testing.T, h *JwtHandler) string {
+				token, err := h.ReleaseToken(7)
+				assert.NoError(t, err)
+				return "Bearer " + token
+			},
+			status: http.StatusOK,
+			body:   `{"userID":7}`,
+		},
+		{
+			name:   "MissingHeader",
+			config: func() *Config { return &Config{Expires: 3600} },
+			header: func(t *testing.T, h *JwtHandler) string { return "" },
+			status: http.StatusUnauthorized,
+			body:   `{"error":"Authorization header required"}`,
+		},
+		{
+			name:   "InvalidFormat",
+			config: func() *Config { return &Config{Expires: 3600} },
+			header: func(t *testing.T, h *JwtHandler) string { return "Token abc" },
+			status: http.StatusUnauthorized,
+			body:   `{"error":"Invalid authorization format"}`,
+		},
+		{
+			name:   "InvalidToken",
+			config: func() *Config { return &Config{Expires: 3600} },
+			header: func(t *testing.T, h *JwtHandler) string { return "Bearer invalid.token.string" },
+			status: http.StatusUnauthorized,
+			body:   `{"error":"Invalid token"}`,
+		},
+		{
+			name:   "Revoked",
+			config: func() *Config { return &Config{Expires: 3600} },
+			header: func(t *testing.T, h *JwtHandler) string {
+				token, err := h.ReleaseToken(7)
+				assert.NoError(t, err)
+				assert.NoError(t, h.RevokeToken(token))
+				return "Bearer " + token
+			},
+			status: http.StatusUnauthorized,
+			body:   `{"error":"Token revoked"}`,
+		},
+		{
+			name:   "GraceRenewal",
+			config: func() *Config { return &Config{Expires: -1, GracePeriod: 60} },
+			header: func(t *testing.T, h *JwtHandler) string {
+				token, err := h.ReleaseToken(7)
+				assert.NoError(t, err)
+				return "Bearer " + token
+			},
+			status: http.StatusOK,
+			body:   `{"userID":7}`,
+		},
+		{
+			name: "StoreUnavailable",
+			config: func() *Config {
+				store, _ := NewStore(CacheConfig{Type: "memory"})
+				return &Config{
+					Expires: 3600,
+					Store:   &slowStore{Store: store, err: errors.New("connection refused")},
+					Breaker: BreakerConfig{RevocationPolicy: RevocationFailClosed},
+				}
+			},
+			header: func(t *testing.T, h *JwtHandler) string {
+				token, err := h.ReleaseToken(7)
+				assert.NoError(t, err)
+				return "Bearer " + token
+			},
+			status: http.StatusServiceUnavailable,
+			body:   `{"error":"Token store unavailable"}`,
+		},
+	}
+
+	// 测试用例1-7: 各场景下所有中间件的行为一致
+	for _, sc := range scenarios {
+		sc := sc
+		t.Run(sc.name, func(t *testing.T) {
+			var results []*httptest.ResponseRecorder
+			for _, router := range routers {
+				h := newTestHandler(t, sc.config())
+				req := httptest.NewRequest("GET", "/grace", nil)
+				if header := sc.header(t, h); header != "" {
+					req.Header.Set("Authorization", header)
+				}
+				w := httptest.NewRecorder()
+				router.setup(h).ServeHTTP(w, req)
+				results = append(results, w)
+			}
+
+			ginRes := results[0]
+			for i, res := range results {
+				name := routers[i].name
+				assert.Equal(t, sc.status, res.Code, name)
+				assert.JSONEq(t, sc.body, res.Body.String(), name)
+				if sc.status != http.StatusOK {
+					assert.Equal(t, ginRes.Body.String(), res.Body.String(), "%s错误响应体逐字节一致", name)
+					assert.Equal(t, ginRes.Header().Get("Content-Type"), res.Header().Get("Content-Type"), name)
+				}
+				assert.Equal(t, ginRes.Header().Get("Authorization") != "", res.Header().Get("Authorization") != "", name)
+				if sc.name == "GraceRenewal" {
+					assert.Contains(t, res.Header().Get("Authorization"), "Bearer ", "%s宽限期续期通过响应头返回新Token", name)
+				}
+			}
+		})
+	}
+
+	// 测试用例8: Gin中间件同样将Claims写入请求ctx
+	t.Run("GinContextClaims", func(t *testing.T) {
+		h := newTestHandler(t, &Config{Expires: 3600, Issuer: "test-issuer"})
+		r := gin.New()
+		r.Use(h.GinMiddleware())
+		r.GET("/grace", func(c *gin.Context) {
+			claims, ok := ClaimsFromContext(c.Request.Context())
+			assert.True(t, ok)
+			assert.Equal(t, "test-issuer", claims.Issuer)
+			c.Status(http.StatusOK)
+		})
+		token, err := h.ReleaseToken(9)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, performRequest(r, token).Code)
+	})
+
+	// 测试用例9: 未经过中间件的ctx读取不到Claims
+	t.Run("NoClaims", func(t *testing.T) {
+		_, ok := ClaimsFromContext(httptest.NewRequest("GET", "/", nil).Context())
+		assert.False(t, ok)
+		_, ok = UserIdFromContext(httptest.NewRequest("GET", "/", nil).Context())
+		assert.False(t, ok)
+	})
+}
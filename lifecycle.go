@@ -0,0 +1,113 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 10:05:17
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 10:05:17
+ * Description: 生命周期管理
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrHandlerClosed 处理器已关闭
+var ErrHandlerClosed = errors.New("jwt处理器已关闭")
+
+// startWorker 启动受生命周期管理的后台协程，关闭处理器时统一停止
+func (j *JwtHandler) startWorker(fn func(quit <-chan struct{})) {
+	j.workers.Add(1)
+	go func() {
+		defer j.workers.Done()
+		fn(j.quit)
+	}()
+}
+
+// startTicker 按固定间隔执行任务，直到处理器关闭
+func (j *JwtHandler) startTicker(interval time.Duration, fn func()) {
+	j.startWorker(func(quit <-chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fn()
+			case <-quit:
+				return
+			}
+		}
+	})
+}
+
+// isClosed 处理器是否已开始关闭
+func (j *JwtHandler) isClosed() bool {
+	select {
+	case <-j.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown 停止所有后台任务，将宽限期内的Token写入黑名单，并关闭存储
+// 可重复调用，后续调用返回首次关闭的结果
+func (j *JwtHandler) Shutdown(ctx context.Context) error {
+	j.closeOnce.Do(func() {
+		j.closeErr = j.shutdown(ctx)
+	})
+	return j.closeErr
+}
+
+// Close 使用不限时的上下文关闭处理器
+func (j *JwtHandler) Close() {
+	_ = j.Shutdown(context.Background())
+}
+
+func (j *JwtHandler) shutdown(ctx context.Context) error {
+	close(j.quit)
+
+	var errs []error
+
+	// 1. 停止调度器及其他后台协程
+	stopped := make(chan struct{})
+	go func() {
+		j.scheduler.Stop()
+		j.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		// 2. 宽限期内的Token不再等待调度，直接加入黑名单
+		if err := j.flushGraceTokens(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("等待后台任务退出超时: %w", ctx.Err()))
+	}
+
+	// 3. 无论是否超时都关闭存储，避免连接泄漏
+	if err := j.store.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("关闭存储失败: %v", err))
+	}
+	return errors.Join(errs...)
+}
+
+// flushGraceTokens 撤销所有待转入黑名单的宽限期Token
+func (j *JwtHandler) flushGraceTokens(ctx context.Context) error {
+	for _, tokenStr := range j.scheduler.Pending() {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("写入待撤销Token中断: %w", err)
+		}
+		if err := j.RevokeTokenContext(ctx, tokenStr); err != nil {
+			return fmt.Errorf("写入待撤销Token失败: %w", err)
+		}
+		_ = j.store.DeleteGrace(ctx, j.TokenKey(tokenStr))
+		j.scheduler.Cancel(tokenStr)
+	}
+	return nil
+}
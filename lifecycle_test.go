@@ -0,0 +1,102 @@
+package gosjwt
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLifecycleHandler(t *testing.T) *JwtHandler {
+	t.Helper()
+	return newTestHandler(t, &Config{
+		Issuer:      "test-issuer",
+		Expires:     -1,
+		GracePeriod: 60,
+	})
+}
+
+func TestShutdown(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 重复关闭安全
+	t.Run("Idempotent", func(t *testing.T) {
+		handler := newLifecycleHandler(t)
+		assert.NoError(t, handler.Shutdown(context.Background()))
+		assert.NoError(t, handler.Shutdown(context.Background()))
+		assert.NotPanics(t, handler.Close)
+
+		_, err := handler.ReleaseToken(1)
+		assert.ErrorIs(t, err, ErrHandlerClosed)
+	})
+
+	// 测试用例2: 关闭时写入待撤销的宽限期Token
+	t.Run("FlushPendingRevocations", func(t *testing.T) {
+		handler := newLifecycleHandler(t)
+		r := setupGraceRouter(handler)
+
+		token, err := handler.ReleaseToken(42)
+		assert.NoError(t, err)
+		w := performRequest(r, token)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, 1, handler.SchedulerStats().QueueDepth)
+
+		// 关闭存储前先检查黑名单，写入后不应再有宽限期记录
+		assert.NoError(t, handler.flushGraceTokens(context.Background()))
+		exists, err := handler.store.IsRevoked(ctx, handler.TokenKey(token))
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		_, found, err := handler.store.GetGrace(ctx, handler.TokenKey(token))
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		assert.NoError(t, handler.Shutdown(context.Background()))
+		assert.Equal(t, 0, handler.SchedulerStats().QueueDepth)
+	})
+
+	// 测试用例3: 上下文已取消时返回错误但仍释放资源
+	t.Run("ContextCanceled", func(t *testing.T) {
+		handler := newLifecycleHandler(t)
+		handler.startWorker(func(quit <-chan struct{}) {
+			<-quit
+			time.Sleep(200 * time.Millisecond) // 模拟退出缓慢的后台任务
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := handler.Shutdown(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, err, handler.Shutdown(context.Background()))
+	})
+}
+
+func TestShutdownNoGoroutineLeak(t *testing.T) {
+	// 等待前面用例遗留的协程退出
+	settle := func() int {
+		runtime.GC()
+		time.Sleep(20 * time.Millisecond)
+		return runtime.NumGoroutine()
+	}
+	before := settle()
+
+	// 每轮在子测试中创建处理器，子测试结束时清理并释放引用
+	for i := 0; i < 5; i++ {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			handler := newLifecycleHandler(t)
+			r := setupGraceRouter(handler)
+			token, err := handler.ReleaseToken(uint(i + 1))
+			assert.NoError(t, err)
+			performRequest(r, token)
+			handler.startTicker(time.Millisecond, func() {})
+			assert.NoError(t, handler.Shutdown(context.Background()))
+		})
+	}
+
+	// 内存缓存的清理协程依赖GC回收，需多轮等待
+	// Eventually 在单独的协程中执行检查函数，计数时需扣除
+	assert.Eventually(t, func() bool {
+		return settle()-1 <= before
+	}, 5*time.Second, 50*time.Millisecond, "关闭后仍有协程未退出")
+}
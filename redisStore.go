@@ -0,0 +1,432 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 19:45:12
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 19:45:12
+ * Description: Redis存储，支持单机、Sentinel、Cluster、TLS与ACL
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRedisPoolSize     = 100
+	defaultRedisMinIdleConns = 20
+)
+
+// redisStore Redis存储
+// 值的编码与goscache保持一致（外层再做一次JSON编码），升级前写入的数据仍可读取
+type redisStore struct {
+	client  redis.UniversalClient
+	prefix  string
+	channel string
+}
+
+// raiseEpochScript 原子地提高全局撤销时间点
+var raiseEpochScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local epoch = tonumber(ARGV[1])
+if epoch > current then
+	redis.call('SET', KEYS[1], ARGV[1])
+	return epoch
+end
+return current
+`)
+
+// newRedisStore 创建Redis存储，连接失败时直接返回错误
+func newRedisStore(cfg CacheConfig) (*redisStore, error) {
+	opts, err := redisOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewUniversalClient(opts)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接Redis失败: %v", err)
+	}
+	return &redisStore{
+		client:  client,
+		prefix:  cfg.Prefix,
+		channel: cfg.Prefix + "revocations",
+	}, nil
+}
+
+// redisOptions 按配置生成客户端选项，设置RedisMasterName时使用Sentinel，
+// 设置RedisCluster或RedisAddrs有多个地址时使用Cluster，否则连接单机
+func redisOptions(cfg CacheConfig) (*redis.UniversalOptions, error) {
+	addrs := cfg.RedisAddrs
+	if len(addrs) == 0 {
+		addrs = []string{cfg.RedisAddr}
+	}
+	cluster := cfg.RedisCluster || (cfg.RedisMasterName == "" && len(addrs) > 1)
+	if cluster && cfg.RedisMasterName != "" {
+		return nil, fmt.Errorf("RedisMasterName与RedisCluster不能同时设置")
+	}
+	if cluster && cfg.RedisDB != 0 {
+		return nil, fmt.Errorf("Redis Cluster不支持RedisDB")
+	}
+
+	tlsConfig, err := cfg.RedisTLS.build()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		MasterName:       cfg.RedisMasterName,
+		IsClusterMode:    cluster,
+		Username:         cfg.RedisUsername,
+		Password:         cfg.RedisPass,
+		DB:               cfg.RedisDB,
+		SentinelUsername: cfg.SentinelUsername,
+		SentinelPassword: cfg.SentinelPassword,
+		PoolSize:         defaultRedisPoolSize,
+		MinIdleConns:     defaultRedisMinIdleConns,
+		TLSConfig:        tlsConfig,
+	}
+	if cfg.RedisPoolSize > 0 {
+		opts.PoolSize = cfg.RedisPoolSize
+	}
+	if cfg.RedisMinIdleConns > 0 {
+		opts.MinIdleConns = cfg.RedisMinIdleConns
+	}
+	return opts, nil
+}
+
+// build 生成tls.Config，未启用时返回nil
+func (c TLSConfig) build() (*tls.Config, error) {
+	if c.Config != nil {
+		return c.Config, nil
+	}
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("解析CA证书失败: %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// setJSON 与goscache一致：结构体编码为JSON字符串后再整体编码
+func (s *redisStore) setJSON(ctx context.Context, fullKey string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("编码存储值失败: %v", err)
+	}
+	value, _ := json.Marshal(string(data))
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client.Set(ctx, fullKey, value, ttl).Err()
+}
+
+// getJSON 读取setJSON写入的值，旧版本写入的其他格式视为错误
+func (s *redisStore) getJSON(ctx context.Context, fullKey string, v interface{}) (bool, error) {
+	value, err := s.client.Get(ctx, fullKey).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var raw string
+	if err := json.Unmarshal(value, &raw); err != nil {
+		return false, fmt.Errorf("无法识别的存储值: %s", fullKey)
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return false, fmt.Errorf("解码存储值失败: %v", err)
+	}
+	return true, nil
+}
+
+func (s *redisStore) del(ctx context.Context, fullKey string) error {
+	return s.client.Del(ctx, fullKey).Err()
+}
+
+// scanKeys 列出匹配的键，Cluster模式下遍历所有主节点
+func (s *redisStore) scanKeys(ctx context.Context, match string) ([]string, error) {
+	var (
+		mu   sync.Mutex
+		keys []string
+	)
+	scan := func(ctx context.Context, c redis.Cmdable) error {
+		iter := c.Scan(ctx, 0, match, 500).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			keys = append(keys, iter.Val())
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	if cluster, ok := s.client.(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scan(ctx, node)
+		})
+		return keys, err
+	}
+	return keys, scan(ctx, s.client)
+}
+
+func (s *redisStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	return s.setJSON(ctx, s.prefix+"token:"+key, rec, ttl)
+}
+
+func (s *redisStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	var rec TokenRecord
+	found, err := s.getJSON(ctx, s.prefix+"token:"+key, &rec)
+	if err != nil || !found {
+		return TokenRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (s *redisStore) DeleteToken(ctx context.Context, key string) error {
+	return s.del(ctx, s.prefix+"token:"+key)
+}
+
+func (s *redisStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return s.client.Set(ctx, s.prefix+"blacklist:"+key, "true", ttl).Err()
+}
+
+func (s *redisStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	n, err := s.client.Exists(ctx, s.prefix+"blacklist:"+key).Result()
+	return n > 0, err
+}
+
+func (s *redisStore) GetEpoch(ctx context.Context) (int64, error) {
+	value, err := s.client.Get(ctx, s.prefix+"blacklist:"+revocationEpochKey).Bytes()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("读取全局撤销时间点失败: %v", err)
+	}
+	var val interface{}
+	if err := json.Unmarshal(value, &val); err != nil {
+		return 0, fmt.Errorf("读取全局撤销时间点失败: %v", err)
+	}
+	return parseEpochValue(val)
+}
+
+func (s *redisStore) RaiseEpoch(ctx context.Context, epoch int64) (int64, error) {
+	key := s.prefix + "blacklist:" + revocationEpochKey
+	current, err := raiseEpochScript.Run(ctx, s.client, []string{key}, epoch).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("写入全局撤销时间点失败: %v", err)
+	}
+	return current, nil
+}
+
+func (s *redisStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return GraceState{}, false, err
+	}
+	value, _ := json.Marshal(string(data))
+	if ttl < 0 {
+		ttl = 0
+	}
+	fullKey := s.prefix + "state:" + graceKeyPrefix + key
+	stored, err := s.client.SetNX(ctx, fullKey, value, ttl).Result()
+	if err != nil {
+		return GraceState{}, false, err
+	}
+	if stored {
+		return state, true, nil
+	}
+	existing, _, err := s.GetGrace(ctx, key)
+	return existing, false, err
+}
+
+func (s *redisStore) GetGrace(ctx context.Context, key string) (GraceState, bool, error) {
+	var state GraceState
+	found, err := s.getJSON(ctx, s.prefix+"state:"+graceKeyPrefix+key, &state)
+	return state, found, err
+}
+
+func (s *redisStore) DeleteGrace(ctx context.Context, key string) error {
+	return s.del(ctx, s.prefix+"state:"+graceKeyPrefix+key)
+}
+
+func (s *redisStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	if sess.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	return s.setJSON(ctx, s.prefix+"state:"+sessionKeyPrefix+sess.ID, sess, ttl)
+}
+
+func (s *redisStore) GetSession(ctx context.Context, id string) (Session, bool, error) {
+	var sess Session
+	found, err := s.getJSON(ctx, s.prefix+"state:"+sessionKeyPrefix+id, &sess)
+	return sess, found, err
+}
+
+func (s *redisStore) DeleteSession(ctx context.Context, id string) error {
+	return s.del(ctx, s.prefix+"state:"+sessionKeyPrefix+id)
+}
+
+func (s *redisStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	prefix := s.prefix + "blacklist:"
+	snapshot := make(map[string]time.Time)
+	now := time.Now()
+
+	keys, err := s.scanKeys(ctx, prefix+"*")
+	for _, fullKey := range keys {
+		key := strings.TrimPrefix(fullKey, prefix)
+		if key == revocationEpochKey {
+			continue
+		}
+		ttl, err := s.client.PTTL(ctx, fullKey).Result()
+		if err != nil || ttl == -2*time.Nanosecond {
+			continue // 键已过期或读取失败，下轮再校准
+		}
+		if ttl < 0 {
+			ttl = defaultRevocationTTL // 无过期时间的记录按默认时长保留
+		}
+		snapshot[key] = now.Add(ttl)
+	}
+	return snapshot, err
+}
+
+// ExportState 扫描撤销记录、宽限期状态与会话并读取剩余过期时间，扫描期间过期的键被跳过
+func (s *redisStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	var records []StateRecord
+	sources := []struct {
+		kind   string
+		prefix string
+	}{
+		{StateRevocation, s.prefix + "blacklist:"},
+		{StateGrace, s.prefix + "state:" + graceKeyPrefix},
+		{StateSession, s.prefix + "state:" + sessionKeyPrefix},
+	}
+	for _, src := range sources {
+		keys, err := s.scanKeys(ctx, src.prefix+"*")
+		if err != nil {
+			return nil, err
+		}
+		for _, fullKey := range keys {
+			key := strings.TrimPrefix(fullKey, src.prefix)
+			if src.kind == StateRevocation && key == revocationEpochKey {
+				continue
+			}
+			ttl, err := s.client.PTTL(ctx, fullKey).Result()
+			if err != nil {
+				return nil, err
+			}
+			if ttl == -2*time.Nanosecond {
+				continue
+			}
+			rec := StateRecord{Kind: src.kind, ExpiresAt: expiryMillis(ttl)}
+			found := true
+			switch src.kind {
+			case StateRevocation:
+				rec.Key = key
+			case StateGrace:
+				rec.Key, rec.Grace = key, &GraceState{}
+				found, err = s.getJSON(ctx, fullKey, rec.Grace)
+			case StateSession:
+				rec.Session = &Session{}
+				found, err = s.getJSON(ctx, fullKey, rec.Session)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				records = append(records, rec)
+			}
+		}
+	}
+	return records, nil
+}
+
+func (s *redisStore) PublishRevocation(ctx context.Context, ev RevocationEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	return s.client.Publish(ctx, s.channel, payload).Err()
+}
+
+func (s *redisStore) SubscribeRevocations(handle func(ev RevocationEvent)) (func(), error) {
+	ctx := context.Background()
+	pubsub := s.client.Subscribe(ctx, s.channel)
+	// 确认订阅生效，避免订阅完成前的事件丢失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range pubsub.ChannelWithSubscriptions() {
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				// 首次订阅的确认已由Receive读取，此后的确认来自断线重连，期间的事件可能已丢失
+				if msg.Kind == "subscribe" {
+					handle(RevocationEvent{Type: "resync"})
+				}
+			case *redis.Message:
+				var ev RevocationEvent
+				if err := json.Unmarshal([]byte(msg.Payload), &ev); err == nil {
+					handle(ev)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			pubsub.Close()
+			<-done
+		})
+	}
+	return stop, nil
+}
+
+func (s *redisStore) status() StoreStatus {
+	return StoreStatus{Backend: "redis"}
+}
+
+func (s *redisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,220 @@
+package gosjwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert 测试用证书及其PEM文件路径
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// issueTestCert 生成证书，parent为nil时自签名为CA
+func issueTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	assert.NoError(t, os.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return c
+}
+
+// exerciseRedisHandler 签发、撤销并校验Token
+func exerciseRedisHandler(t *testing.T, cache CacheConfig) {
+	t.Helper()
+	handler, err := NewJwtHandler(&Config{SigningKey: []byte("redis-store-key"), Expires: 3600, Cache: cache})
+	assert.NoError(t, err)
+	defer handler.Close()
+	assert.Equal(t, "redis", handler.StoreStatus().Backend)
+	assert.False(t, handler.StoreStatus().Degraded)
+
+	token, err := handler.ReleaseToken(1)
+	assert.NoError(t, err)
+	_, claims, err := handler.ParseToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserId)
+
+	assert.NoError(t, handler.RevokeToken(token))
+	_, _, err = handler.ParseToken(token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+	snapshot, err := handler.revocationSnapshot()
+	assert.NoError(t, err)
+	assert.Contains(t, snapshot, handler.TokenKey(token))
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 按配置选择部署模式
+	t.Run("Options", func(t *testing.T) {
+		opts, err := redisOptions(CacheConfig{RedisAddr: "127.0.0.1:6379"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"127.0.0.1:6379"}, opts.Addrs)
+		assert.Equal(t, defaultRedisPoolSize, opts.PoolSize)
+		assert.Equal(t, defaultRedisMinIdleConns, opts.MinIdleConns)
+		assert.Nil(t, opts.TLSConfig)
+
+		opts, err = redisOptions(CacheConfig{
+			RedisAddrs:        []string{"s1:26379", "s2:26379"},
+			RedisMasterName:   "mymaster",
+			RedisUsername:     "app",
+			SentinelPassword:  "sentinel-pass",
+			RedisPoolSize:     8,
+			RedisMinIdleConns: 2,
+		})
+		assert.NoError(t, err)
+		assert.False(t, opts.IsClusterMode)
+		assert.Equal(t, "mymaster", opts.Failover().MasterName)
+		assert.Equal(t, []string{"s1:26379", "s2:26379"}, opts.Failover().SentinelAddrs)
+		assert.Equal(t, "app", opts.Failover().Username)
+		assert.Equal(t, "sentinel-pass", opts.Failover().SentinelPassword)
+		assert.Equal(t, 8, opts.PoolSize)
+		assert.Equal(t, 2, opts.MinIdleConns)
+
+		opts, err = redisOptions(CacheConfig{RedisAddrs: []string{"n1:6379", "n2:6379"}})
+		assert.NoError(t, err)
+		assert.True(t, opts.IsClusterMode)
+
+		_, err = redisOptions(CacheConfig{RedisAddr: "n1:6379", RedisCluster: true, RedisDB: 1})
+		assert.Error(t, err)
+		_, err = redisOptions(CacheConfig{RedisAddr: "n1:6379", RedisCluster: true, RedisMasterName: "mymaster"})
+		assert.Error(t, err)
+		_, err = redisOptions(CacheConfig{RedisTLS: TLSConfig{Enabled: true, CAFile: "missing.pem"}})
+		assert.Error(t, err)
+	})
+
+	// 测试用例2: ACL用户名认证
+	t.Run("ACL", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		mr.RequireUserAuth("app", "app-pass")
+
+		_, err := NewStore(CacheConfig{Type: "redis", RedisAddr: mr.Addr(), RedisPass: "app-pass", FailurePolicy: FailureFailClosed})
+		assert.Error(t, err)
+		exerciseRedisHandler(t, CacheConfig{
+			Type:          "redis",
+			RedisAddr:     mr.Addr(),
+			RedisUsername: "app",
+			RedisPass:     "app-pass",
+			FailurePolicy: FailureFailClosed,
+		})
+	})
+
+	// 测试用例3: 自定义CA与客户端证书的双向TLS
+	t.Run("TLS", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := issueTestCert(t, dir, "ca", nil)
+		server := issueTestCert(t, dir, "server", ca)
+		client := issueTestCert(t, dir, "client", ca)
+
+		serverCert, err := tls.LoadX509KeyPair(server.certFile, server.keyFile)
+		assert.NoError(t, err)
+		pool := x509.NewCertPool()
+		pool.AddCert(ca.cert)
+		mr, err := miniredis.RunTLS(&tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+		})
+		assert.NoError(t, err)
+		defer mr.Close()
+
+		cache := CacheConfig{Type: "redis", RedisAddr: mr.Addr(), FailurePolicy: FailureFailClosed}
+		// 未启用TLS或缺少客户端证书时无法连接
+		_, err = NewStore(cache)
+		assert.Error(t, err)
+		cache.RedisTLS = TLSConfig{Enabled: true, CAFile: ca.certFile}
+		_, err = NewStore(cache)
+		assert.Error(t, err)
+
+		cache.RedisTLS.CertFile = client.certFile
+		cache.RedisTLS.KeyFile = client.keyFile
+		exerciseRedisHandler(t, cache)
+	})
+
+	// 测试用例4: Cluster模式（单节点配置端点）
+	t.Run("Cluster", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		store, err := newRedisStore(CacheConfig{RedisAddr: mr.Addr(), RedisCluster: true, Prefix: "c_"})
+		assert.NoError(t, err)
+		_, ok := store.client.(*redis.ClusterClient)
+		assert.True(t, ok)
+		assert.NoError(t, store.Close())
+
+		exerciseRedisHandler(t, CacheConfig{
+			Type:          "redis",
+			RedisAddr:     mr.Addr(),
+			RedisCluster:  true,
+			Prefix:        "c_",
+			FailurePolicy: FailureFailClosed,
+		})
+	})
+
+	// 测试用例5: 与goscache写入的数据格式兼容
+	t.Run("LegacyEncoding", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		store, err := newRedisStore(CacheConfig{RedisAddr: mr.Addr(), Prefix: "p_"})
+		assert.NoError(t, err)
+		defer store.Close()
+
+		assert.NoError(t, mr.Set("p_token:k", `"{\"user_id\":7,\"expires_at\":100}"`))
+		rec, found, err := store.GetToken(ctx, "k")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, uint(7), rec.UserId)
+
+		assert.NoError(t, mr.Set("p_blacklist:"+revocationEpochKey, "1700000000"))
+		epoch, err := store.GetEpoch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1700000000), epoch)
+	})
+}
@@ -0,0 +1,239 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 13:02:45
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 13:02:45
+ * Description: 本地撤销集合与集群同步
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultRevocationSyncInterval 本地撤销集合全量校准的默认间隔，即广播丢失时其他实例的撤销在本实例生效的最大延迟
+const defaultRevocationSyncInterval = 10 * time.Second
+
+// revocationSet 本地撤销集合，记录Token及其撤销记录的过期时间
+type revocationSet struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func newRevocationSet() *revocationSet {
+	return &revocationSet{entries: make(map[string]time.Time)}
+}
+
+// Add 加入撤销集合，expiresAt之后自动失效
+func (s *revocationSet) Add(key string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.entries[key]; !ok || expiresAt.After(current) {
+		s.entries[key] = expiresAt
+	}
+}
+
+// Contains 是否已撤销且撤销记录未过期
+func (s *revocationSet) Contains(key string) bool {
+	s.mu.RLock()
+	expiresAt, ok := s.entries[key]
+	s.mu.RUnlock()
+	return ok && time.Now().Before(expiresAt)
+}
+
+// Len 当前记录数（含尚未清理的过期记录）
+func (s *revocationSet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Merge 合并全量快照
+func (s *revocationSet) Merge(snapshot map[string]time.Time) {
+	for key, expiresAt := range snapshot {
+		s.Add(key, expiresAt)
+	}
+}
+
+// Purge 清理已过期的记录
+func (s *revocationSet) Purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, expiresAt := range s.entries {
+		if !now.Before(expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// checkRevocationCache 本地撤销集合与布隆过滤器只在存储可枚举撤销记录时才能获得其他实例的撤销，
+// 包装层（如熔断）总是实现RevocationLister，需检查被包装的存储
+func checkRevocationCache(config *Config, store Store) error {
+	if _, ok := baseStore(store).(RevocationLister); ok {
+		return nil
+	}
+	if config.LocalRevocation {
+		return fmt.Errorf("启用本地撤销集合失败: 存储未实现RevocationLister，无法获取其他实例的撤销记录")
+	}
+	if config.RevocationFilter.Enabled {
+		return fmt.Errorf("启用布隆过滤器失败: 存储未实现RevocationLister，无法获取其他实例的撤销记录")
+	}
+	return nil
+}
+
+// initRevocationCache 启用本地撤销集合或布隆过滤器，存储支持广播时订阅撤销事件并定期全量校准
+func (j *JwtHandler) initRevocationCache() {
+	filterCfg := j.Config.RevocationFilter
+	if !j.Config.LocalRevocation && !filterCfg.Enabled {
+		return
+	}
+
+	if j.Config.LocalRevocation {
+		j.revoked = newRevocationSet()
+	}
+	if filterCfg.Enabled {
+		j.filter = newRevocationFilter(filterCfg)
+	}
+	j.resync = make(chan struct{}, 1)
+
+	if n, ok := j.store.(RevocationNotifier); ok {
+		j.notifier = n
+		// 订阅失败时仍依赖定期全量校准，撤销延迟不超过校准间隔
+		if stop, err := n.SubscribeRevocations(j.applyRevocationEvent); err == nil {
+			j.startWorker(func(quit <-chan struct{}) {
+				<-quit
+				stop()
+			})
+		}
+	}
+
+	// 内存存储下撤销只发生在本进程，本地记录即完整视图，仅需定期清理
+	j.refreshRevocationCache()
+	j.startWorker(j.runRevocationSync)
+}
+
+// refreshRevocationCache 校准本地撤销集合并重建布隆过滤器
+func (j *JwtHandler) refreshRevocationCache() {
+	if j.revoked != nil {
+		j.syncRevocations()
+	}
+	if j.filter != nil {
+		j.rebuildFilter()
+	}
+}
+
+// runRevocationSync 按各自的间隔校准本地撤销集合与重建布隆过滤器，订阅重新建立时立即执行，
+// 两者在同一协程中执行，不会并发重建
+func (j *JwtHandler) runRevocationSync(quit <-chan struct{}) {
+	var syncC, rebuildC <-chan time.Time
+	if j.revoked != nil {
+		interval := defaultRevocationSyncInterval
+		if j.Config.RevocationSyncInterval > 0 {
+			interval = time.Duration(j.Config.RevocationSyncInterval) * time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	if j.filter != nil {
+		interval := defaultFilterRebuildInterval
+		if j.Config.RevocationFilter.RebuildInterval > 0 {
+			interval = time.Duration(j.Config.RevocationFilter.RebuildInterval) * time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		rebuildC = ticker.C
+	}
+
+	for {
+		select {
+		case <-syncC:
+			j.syncRevocations()
+		case <-rebuildC:
+			j.rebuildFilter()
+		case <-j.resync:
+			j.refreshRevocationCache()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// revocationSnapshot 拉取共享黑名单的全量快照，NewJwtHandler已确保存储支持枚举
+func (j *JwtHandler) revocationSnapshot() (map[string]time.Time, error) {
+	lister, ok := j.store.(RevocationLister)
+	if !ok {
+		return nil, fmt.Errorf("存储未实现RevocationLister")
+	}
+	ctx, cancel := j.storeContext(context.Background())
+	defer cancel()
+	return lister.Revocations(ctx)
+}
+
+// syncRevocations 拉取全量快照校准本地集合，弥补断线期间丢失的事件
+func (j *JwtHandler) syncRevocations() {
+	if snapshot, err := j.revocationSnapshot(); err == nil {
+		j.revoked.Merge(snapshot)
+	}
+	j.revoked.Purge()
+}
+
+// rebuildFilter 从黑名单快照重建布隆过滤器，拉取失败时保留当前过滤器
+func (j *JwtHandler) rebuildFilter() {
+	j.filter.BeginRebuild()
+	snapshot, err := j.revocationSnapshot()
+	if err != nil {
+		j.filter.AbortRebuild()
+		return
+	}
+	j.filter.FinishRebuild(snapshot)
+}
+
+// applyRevocationEvent 处理其他实例广播的撤销事件
+func (j *JwtHandler) applyRevocationEvent(ev RevocationEvent) {
+	switch ev.Type {
+	case "revoke":
+		j.recordRevocation(ev.Key, time.UnixMilli(ev.ExpiresAt))
+	case "epoch":
+		j.storeEpoch(ev.Epoch)
+	case "resync":
+		// 断线期间的事件可能已丢失，不等下一个周期立即校准
+		select {
+		case j.resync <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// publishRevocation 本地记录并广播撤销事件
+func (j *JwtHandler) publishRevocation(ctx context.Context, key string, ttl time.Duration) {
+	expiresAt := time.Now().Add(ttl)
+	j.recordRevocation(key, expiresAt)
+	if j.notifier != nil {
+		_ = j.notifier.PublishRevocation(ctx, RevocationEvent{Type: "revoke", Key: key, ExpiresAt: expiresAt.UnixMilli()})
+	}
+}
+
+// recordRevocation 写入本地撤销集合与布隆过滤器
+func (j *JwtHandler) recordRevocation(key string, expiresAt time.Time) {
+	if j.revoked != nil {
+		j.revoked.Add(key, expiresAt)
+	}
+	if j.filter != nil {
+		j.filter.Add(key)
+	}
+}
+
+// publishEpoch 广播全局撤销时间点，使其他实例无需等待定期同步
+func (j *JwtHandler) publishEpoch(ctx context.Context, epoch int64) {
+	if j.notifier != nil {
+		_ = j.notifier.PublishRevocation(ctx, RevocationEvent{Type: "epoch", Epoch: epoch})
+	}
+}
@@ -0,0 +1,173 @@
+package gosjwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// lookupCounter 统计黑名单读取次数的存储包装
+type lookupCounter struct {
+	Store
+	lookups int
+}
+
+func (c *lookupCounter) IsRevoked(ctx context.Context, key string) (bool, error) {
+	c.lookups++
+	return c.Store.IsRevoked(ctx, key)
+}
+
+func TestLocalRevocation(t *testing.T) {
+	// 测试用例1: 内存缓存下鉴权不再访问黑名单
+	t.Run("NoCacheLookupOnHotPath", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{
+			Expires:         3600,
+			LocalRevocation: true,
+		})
+
+		counter := &lookupCounter{Store: handler.store}
+		handler.store = counter
+		r := setupGraceRouter(handler)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, performRequest(r, token).Code)
+
+		assert.NoError(t, handler.RevokeToken(token))
+		w := performRequest(r, token)
+		assert.Equal(t, 401, w.Code)
+		assert.Contains(t, w.Body.String(), "Token revoked")
+		assert.Equal(t, 0, counter.lookups)
+	})
+
+	// 测试用例2: Redis发布订阅将撤销同步到其他实例
+	t.Run("RedisPubSub", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		newConfig := func() *Config {
+			config := newRedisTestConfig(mr.Addr())
+			config.LocalRevocation = true
+			return config
+		}
+
+		a := newTestHandler(t, newConfig())
+		b := newTestHandler(t, newConfig())
+
+		token, err := a.ReleaseToken(2)
+		assert.NoError(t, err)
+		_, _, err = b.ParseToken(token)
+		assert.NoError(t, err)
+
+		assert.NoError(t, a.RevokeToken(token))
+		assert.Eventually(t, func() bool {
+			return isRevokedForTest(b, token)
+		}, time.Second, 10*time.Millisecond)
+
+		// 全局撤销时间点同样即时广播
+		later := time.Now().Add(30 * time.Second).Truncate(time.Second)
+		assert.NoError(t, a.RevokeIssuedBefore(later))
+		assert.Eventually(t, func() bool {
+			return b.RevocationEpoch().Equal(later)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	// 测试用例3: 新实例启动时全量加载已有撤销记录
+	t.Run("RedisSnapshotOnStart", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		newConfig := func() *Config {
+			config := newRedisTestConfig(mr.Addr())
+			config.LocalRevocation = true
+			return config
+		}
+
+		a := newTestHandler(t, newConfig())
+
+		token, err := a.ReleaseToken(3)
+		assert.NoError(t, err)
+		assert.NoError(t, a.RevokeToken(token))
+
+		b := newTestHandler(t, newConfig())
+		assert.True(t, isRevokedForTest(b, token))
+		assert.Equal(t, 1, b.revoked.Len())
+	})
+
+	// 测试用例4: 过期的撤销记录被清理
+	t.Run("Purge", func(t *testing.T) {
+		set := newRevocationSet()
+		set.Add("expired", time.Now().Add(-time.Second))
+		set.Add("live", time.Now().Add(time.Hour))
+		assert.False(t, set.Contains("expired"))
+		assert.True(t, set.Contains("live"))
+
+		set.Purge()
+		assert.Equal(t, 1, set.Len())
+	})
+	// 测试用例5: 存储无法枚举撤销记录时拒绝启用，熔断包装不能掩盖
+	t.Run("RequiresLister", func(t *testing.T) {
+		inner, err := NewStore(CacheConfig{Type: "memory"})
+		assert.NoError(t, err)
+
+		for _, breaker := range []bool{false, true} {
+			_, err := NewJwtHandler(&Config{
+				SigningKey:      []byte("local-revocation-key"),
+				LocalRevocation: true,
+				Store:           plainStore{Store: inner},
+				Breaker:         BreakerConfig{Enabled: breaker},
+			})
+			assert.Error(t, err, "breaker=%v", breaker)
+
+			_, err = NewJwtHandler(&Config{
+				SigningKey:       []byte("local-revocation-key"),
+				RevocationFilter: FilterConfig{Enabled: true},
+				Store:            plainStore{Store: inner},
+				Breaker:          BreakerConfig{Enabled: breaker},
+			})
+			assert.Error(t, err, "filter breaker=%v", breaker)
+		}
+
+		// 内置内存存储可枚举，经熔断包装后仍能校准
+		handler := newTestHandler(t, &Config{
+			Expires:         3600,
+			LocalRevocation: true,
+			Store:           inner,
+			Breaker:         BreakerConfig{Enabled: true},
+		})
+		token, err := handler.ReleaseToken(5)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		snapshot, err := handler.revocationSnapshot()
+		assert.NoError(t, err)
+		assert.Contains(t, snapshot, handler.TokenKey(token))
+	})
+
+	// 测试用例6: 断线期间丢失的撤销事件在重新订阅后立即校准，不等待校准周期
+	t.Run("ResyncOnReconnect", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.LocalRevocation = true
+		config.RevocationSyncInterval = 3600 // 排除定期校准
+		handler := newTestHandler(t, config)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.False(t, isRevokedForTest(handler, token))
+
+		// 断线期间其他实例写入的撤销记录收不到广播
+		mr.Close()
+		key := "test_blacklist:" + handler.TokenKey(token)
+		assert.NoError(t, mr.Set(key, "true"))
+		mr.SetTTL(key, time.Minute)
+		assert.NoError(t, mr.Restart())
+
+		assert.Eventually(t, func() bool {
+			return isRevokedForTest(handler, token)
+		}, 10*time.Second, 50*time.Millisecond)
+	})
+}
+
+// plainStore 只实现Store接口的自定义存储
+type plainStore struct {
+	Store
+}
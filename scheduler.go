@@ -0,0 +1,214 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 09:12:40
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 09:12:40
+ * Description: 到期调度器
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// SchedulerStats 调度器运行指标
+type SchedulerStats struct {
+	QueueDepth   int       // 当前排队任务数
+	Scheduled    uint64    // 累计调度任务数
+	Fired        uint64    // 累计触发任务数
+	Canceled     uint64    // 累计取消任务数
+	NextDeadline time.Time // 最近一个任务的触发时间，队列为空时为零值
+}
+
+// expiryTask 调度任务
+type expiryTask struct {
+	key      string
+	deadline time.Time
+	index    int
+}
+
+// expiryQueue 按触发时间排序的最小堆
+type expiryQueue []*expiryTask
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, k int) bool { return q[i].deadline.Before(q[k].deadline) }
+
+func (q expiryQueue) Swap(i, k int) {
+	q[i], q[k] = q[k], q[i]
+	q[i].index = i
+	q[k].index = k
+}
+
+func (q *expiryQueue) Push(x interface{}) {
+	task := x.(*expiryTask)
+	task.index = len(*q)
+	*q = append(*q, task)
+}
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.index = -1
+	*q = old[:n-1]
+	return task
+}
+
+// expiryScheduler 统一管理到期任务，单个协程按最小堆顺序触发
+type expiryScheduler struct {
+	mu       sync.Mutex
+	queue    expiryQueue
+	tasks    map[string]*expiryTask // 按key去重
+	onExpire func(key string)
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	scheduled uint64
+	fired     uint64
+	canceled  uint64
+}
+
+func newExpiryScheduler(onExpire func(key string)) *expiryScheduler {
+	s := &expiryScheduler{
+		tasks:    make(map[string]*expiryTask),
+		onExpire: onExpire,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Schedule 在deadline触发key，已存在的任务会被改期
+func (s *expiryScheduler) Schedule(key string, deadline time.Time) {
+	s.mu.Lock()
+	if task, ok := s.tasks[key]; ok {
+		task.deadline = deadline
+		heap.Fix(&s.queue, task.index)
+	} else {
+		task = &expiryTask{key: key, deadline: deadline}
+		heap.Push(&s.queue, task)
+		s.tasks[key] = task
+	}
+	s.scheduled++
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Cancel 取消尚未触发的任务
+func (s *expiryScheduler) Cancel(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[key]
+	if !ok {
+		return
+	}
+	heap.Remove(&s.queue, task.index)
+	delete(s.tasks, key)
+	s.canceled++
+}
+
+// Stats 返回调度器指标快照
+func (s *expiryScheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{
+		QueueDepth: len(s.queue),
+		Scheduled:  s.scheduled,
+		Fired:      s.fired,
+		Canceled:   s.canceled,
+	}
+	if len(s.queue) > 0 {
+		stats.NextDeadline = s.queue[0].deadline
+	}
+	return stats
+}
+
+// Pending 返回尚未触发的任务key
+func (s *expiryScheduler) Pending() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.tasks))
+	for key := range s.tasks {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Stop 停止调度协程，未触发的任务保留在队列中
+func (s *expiryScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+func (s *expiryScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *expiryScheduler) run() {
+	defer close(s.done)
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) > 0 {
+			wait := time.Until(s.queue[0].deadline)
+			if wait < 0 {
+				wait = 0
+			}
+			timer.Reset(wait)
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		for _, key := range s.popDue(time.Now()) {
+			s.onExpire(key)
+		}
+	}
+}
+
+// popDue 取出所有已到期的任务
+func (s *expiryScheduler) popDue(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for len(s.queue) > 0 && !s.queue[0].deadline.After(now) {
+		task := heap.Pop(&s.queue).(*expiryTask)
+		delete(s.tasks, task.key)
+		keys = append(keys, task.key)
+		s.fired++
+	}
+	return keys
+}
@@ -0,0 +1,114 @@
+package gosjwt
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryScheduler(t *testing.T) {
+	// 测试用例1: 按截止时间顺序触发
+	t.Run("FireInOrder", func(t *testing.T) {
+		var mu sync.Mutex
+		var fired []string
+		s := newExpiryScheduler(func(key string) {
+			mu.Lock()
+			fired = append(fired, key)
+			mu.Unlock()
+		})
+		defer s.Stop()
+
+		now := time.Now()
+		s.Schedule("c", now.Add(150*time.Millisecond))
+		s.Schedule("a", now.Add(50*time.Millisecond))
+		s.Schedule("b", now.Add(100*time.Millisecond))
+		assert.Equal(t, 3, s.Stats().QueueDepth)
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(fired) == 3
+		}, time.Second, 10*time.Millisecond)
+
+		mu.Lock()
+		assert.Equal(t, []string{"a", "b", "c"}, fired)
+		mu.Unlock()
+
+		stats := s.Stats()
+		assert.Equal(t, 0, stats.QueueDepth)
+		assert.Equal(t, uint64(3), stats.Fired)
+		assert.True(t, stats.NextDeadline.IsZero())
+	})
+
+	// 测试用例2: 重复调度同一key只保留最新截止时间
+	t.Run("Reschedule", func(t *testing.T) {
+		s := newExpiryScheduler(func(string) {})
+		defer s.Stop()
+
+		deadline := time.Now().Add(time.Hour)
+		s.Schedule("k", time.Now().Add(time.Minute))
+		s.Schedule("k", deadline)
+
+		stats := s.Stats()
+		assert.Equal(t, 1, stats.QueueDepth)
+		assert.Equal(t, uint64(2), stats.Scheduled)
+		assert.True(t, stats.NextDeadline.Equal(deadline))
+	})
+
+	// 测试用例3: 取消后不再触发
+	t.Run("Cancel", func(t *testing.T) {
+		firedCh := make(chan string, 1)
+		s := newExpiryScheduler(func(key string) { firedCh <- key })
+		defer s.Stop()
+
+		s.Schedule("k", time.Now().Add(50*time.Millisecond))
+		s.Cancel("k")
+		assert.Equal(t, 0, s.Stats().QueueDepth)
+		assert.Equal(t, uint64(1), s.Stats().Canceled)
+
+		select {
+		case key := <-firedCh:
+			t.Fatalf("已取消的任务被触发: %s", key)
+		case <-time.After(150 * time.Millisecond):
+		}
+	})
+}
+
+func TestGracePeriodScheduling(t *testing.T) {
+	config := &Config{
+		SigningKey:  []byte("scheduler-test-key"),
+		Issuer:      "test-issuer",
+		Expires:     -1, // 立即过期
+		GracePeriod: 2,  // 截止时间为首次使用后2秒
+		Cache: CacheConfig{
+			Type: "memory",
+		},
+	}
+
+	handler, err := NewJwtHandler(config)
+	assert.NoError(t, err)
+	defer handler.Close()
+
+	r := setupGraceRouter(handler)
+	tokens := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		token, err := handler.ReleaseToken(uint(i + 1))
+		assert.NoError(t, err)
+		tokens = append(tokens, token)
+		w := performRequest(r, token)
+		assert.Equal(t, 200, w.Code)
+	}
+
+	// 所有宽限期Token共用一个调度队列
+	assert.Equal(t, len(tokens), handler.SchedulerStats().QueueDepth)
+
+	// 截止时间过后由调度器转入黑名单
+	assert.Eventually(t, func() bool {
+		return handler.SchedulerStats().QueueDepth == 0
+	}, 5*time.Second, 50*time.Millisecond)
+	for _, token := range tokens {
+		assert.True(t, isRevokedForTest(handler, token))
+	}
+}
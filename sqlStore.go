@@ -0,0 +1,489 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 17:12:40
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 17:12:40
+ * Description: 基于database/sql的存储，支持PostgreSQL与SQLite
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SQLDialectSQLite   = "sqlite"
+	SQLDialectPostgres = "postgres"
+
+	defaultSQLTablePrefix   = "gosjwt_"
+	defaultSQLSweepInterval = time.Minute
+
+	sqlEpochName = "revocation_epoch"
+)
+
+// SQLStoreOptions SQL存储配置
+type SQLStoreOptions struct {
+	Dialect       string // SQLDialectSQLite(默认) 或 SQLDialectPostgres，决定占位符风格
+	TablePrefix   string // 表名前缀，默认 "gosjwt_"
+	SweepInterval int    // 过期记录清理间隔(秒)，默认60秒，小于0时不自动清理
+}
+
+// sqlMigrations 按顺序执行的建表语句，{p} 替换为表名前缀
+// 过期时间列 expiry 为Unix毫秒，0表示不过期；以原始Token为键的历史记录（LegacyTokenKeys、导入）可能很长，键列均为TEXT
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE IF NOT EXISTS {p}tokens (
+			token_key  TEXT PRIMARY KEY,
+			user_id    BIGINT NOT NULL,
+			issued_at  BIGINT NOT NULL,
+			expires_at BIGINT NOT NULL,
+			expiry     BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {p}tokens_expiry ON {p}tokens (expiry)`,
+		`CREATE TABLE IF NOT EXISTS {p}revocations (
+			token_key TEXT PRIMARY KEY,
+			expiry    BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {p}revocations_expiry ON {p}revocations (expiry)`,
+		`CREATE TABLE IF NOT EXISTS {p}grace (
+			token_key     TEXT PRIMARY KEY,
+			deadline      BIGINT NOT NULL,
+			new_token_key TEXT NOT NULL,
+			expiry        BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {p}grace_expiry ON {p}grace (expiry)`,
+		`CREATE TABLE IF NOT EXISTS {p}sessions (
+			id         TEXT PRIMARY KEY,
+			user_id    BIGINT NOT NULL,
+			data       TEXT NOT NULL,
+			expires_at BIGINT NOT NULL,
+			expiry     BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS {p}sessions_user_id ON {p}sessions (user_id)`,
+		`CREATE INDEX IF NOT EXISTS {p}sessions_expiry ON {p}sessions (expiry)`,
+		`CREATE TABLE IF NOT EXISTS {p}meta (
+			name  VARCHAR(64) PRIMARY KEY,
+			value BIGINT NOT NULL
+		)`,
+	},
+}
+
+// SQLStore 基于database/sql的存储，首次使用时自动建表并执行迁移
+// 传入的*sql.DB由调用方管理，Close不会关闭它
+type SQLStore struct {
+	db       *sql.DB
+	dialect  string
+	prefix   string
+	queries  sync.Map // 原始语句 -> 替换前缀与占位符后的语句
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSQLStore 创建SQL存储并执行迁移
+func NewSQLStore(db *sql.DB, opts SQLStoreOptions) (*SQLStore, error) {
+	if opts.Dialect == "" {
+		opts.Dialect = SQLDialectSQLite
+	}
+	if opts.Dialect != SQLDialectSQLite && opts.Dialect != SQLDialectPostgres {
+		return nil, fmt.Errorf("不支持的SQL方言: %s", opts.Dialect)
+	}
+	if opts.TablePrefix == "" {
+		opts.TablePrefix = defaultSQLTablePrefix
+	}
+
+	s := &SQLStore{
+		db:      db,
+		dialect: opts.Dialect,
+		prefix:  opts.TablePrefix,
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := s.migrate(); err != nil {
+		return nil, fmt.Errorf("SQL存储迁移失败: %v", err)
+	}
+
+	if opts.SweepInterval < 0 {
+		close(s.done)
+		return s, nil
+	}
+	interval := defaultSQLSweepInterval
+	if opts.SweepInterval > 0 {
+		interval = time.Duration(opts.SweepInterval) * time.Second
+	}
+	go s.sweepLoop(interval)
+	return s, nil
+}
+
+// query 替换表名前缀，PostgreSQL下将?改写为$n
+func (s *SQLStore) query(raw string) string {
+	if q, ok := s.queries.Load(raw); ok {
+		return q.(string)
+	}
+	q := strings.ReplaceAll(raw, "{p}", s.prefix)
+	if s.dialect == SQLDialectPostgres {
+		var b strings.Builder
+		n := 0
+		for _, r := range q {
+			if r == '?' {
+				n++
+				b.WriteString("$" + strconv.Itoa(n))
+				continue
+			}
+			b.WriteRune(r)
+		}
+		q = b.String()
+	}
+	s.queries.Store(raw, q)
+	return q
+}
+
+// migrate 执行尚未应用的迁移，版本号记录在 {p}schema_migrations
+func (s *SQLStore) migrate() error {
+	if _, err := s.db.Exec(s.query(`CREATE TABLE IF NOT EXISTS {p}schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)); err != nil {
+		return err
+	}
+
+	var current int
+	if err := s.db.QueryRow(s.query(`SELECT COALESCE(MAX(version), 0) FROM {p}schema_migrations`)).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(sqlMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range sqlMigrations[i] {
+			if _, err := tx.Exec(s.query(stmt)); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("执行迁移%d失败: %v", i+1, err)
+			}
+		}
+		// 多实例同时启动时迁移语句均可重复执行，版本记录以先写入者为准
+		if _, err := tx.Exec(s.query(`INSERT INTO {p}schema_migrations (version, applied_at) VALUES (?, ?)
+			ON CONFLICT (version) DO NOTHING`),
+			i+1, time.Now().Unix()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SchemaVersion 当前已应用的迁移版本
+func (s *SQLStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.db.QueryRow(s.query(`SELECT COALESCE(MAX(version), 0) FROM {p}schema_migrations`)).Scan(&version)
+	return version, err
+}
+
+func (s *SQLStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO {p}tokens (token_key, user_id, issued_at, expires_at, expiry) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (token_key) DO UPDATE SET user_id = excluded.user_id, issued_at = excluded.issued_at,
+		expires_at = excluded.expires_at, expiry = excluded.expiry`),
+		key, int64(rec.UserId), rec.IssuedAt, rec.ExpiresAt, expiryMillis(ttl))
+	return err
+}
+
+func (s *SQLStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	var (
+		rec    TokenRecord
+		userId int64
+	)
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT user_id, issued_at, expires_at FROM {p}tokens
+		WHERE token_key = ? AND (expiry = 0 OR expiry > ?)`), key, time.Now().UnixMilli()).
+		Scan(&userId, &rec.IssuedAt, &rec.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TokenRecord{}, false, nil
+	}
+	if err != nil {
+		return TokenRecord{}, false, err
+	}
+	rec.UserId = uint(userId)
+	return rec, true, nil
+}
+
+func (s *SQLStore) DeleteToken(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM {p}tokens WHERE token_key = ?`), key)
+	return err
+}
+
+func (s *SQLStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO {p}revocations (token_key, expiry) VALUES (?, ?)
+		ON CONFLICT (token_key) DO UPDATE SET expiry = excluded.expiry`), key, expiryMillis(ttl))
+	return err
+}
+
+func (s *SQLStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT COUNT(*) FROM {p}revocations
+		WHERE token_key = ? AND (expiry = 0 OR expiry > ?)`), key, time.Now().UnixMilli()).Scan(&n)
+	return n > 0, err
+}
+
+// Revocations 返回未过期的撤销记录，不过期的记录按默认时长保留
+func (s *SQLStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	now := time.Now()
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT token_key, expiry FROM {p}revocations WHERE expiry = 0 OR expiry > ?`), now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshot := make(map[string]time.Time)
+	for rows.Next() {
+		var (
+			key    string
+			expiry int64
+		)
+		if err := rows.Scan(&key, &expiry); err != nil {
+			return nil, err
+		}
+		if expiry == 0 {
+			snapshot[key] = now.Add(defaultRevocationTTL)
+		} else {
+			snapshot[key] = time.UnixMilli(expiry)
+		}
+	}
+	return snapshot, rows.Err()
+}
+
+// ExportState 返回未过期的撤销记录、宽限期状态与会话
+func (s *SQLStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	now := time.Now().UnixMilli()
+	var records []StateRecord
+
+	// 每张表读取完再查询下一张，兼容单连接的数据库
+	scan := func(query string, row func(rows *sql.Rows) (StateRecord, error)) error {
+		rows, err := s.db.QueryContext(ctx, s.query(query), now)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			rec, err := row(rows)
+			if err != nil {
+				return err
+			}
+			records = append(records, rec)
+		}
+		return rows.Err()
+	}
+
+	err := scan(`SELECT token_key, expiry FROM {p}revocations WHERE expiry = 0 OR expiry > ?`,
+		func(rows *sql.Rows) (StateRecord, error) {
+			rec := StateRecord{Kind: StateRevocation}
+			return rec, rows.Scan(&rec.Key, &rec.ExpiresAt)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(`SELECT token_key, deadline, new_token_key, expiry FROM {p}grace WHERE expiry = 0 OR expiry > ?`,
+		func(rows *sql.Rows) (StateRecord, error) {
+			var deadline int64
+			rec := StateRecord{Kind: StateGrace, Grace: &GraceState{}}
+			if err := rows.Scan(&rec.Key, &deadline, &rec.Grace.NewTokenKey, &rec.ExpiresAt); err != nil {
+				return rec, err
+			}
+			rec.Grace.Deadline = time.UnixMilli(deadline)
+			return rec, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = scan(`SELECT id, user_id, data, expires_at, expiry FROM {p}sessions WHERE expiry = 0 OR expiry > ?`,
+		func(rows *sql.Rows) (StateRecord, error) {
+			var (
+				userId    int64
+				data      string
+				expiresAt int64
+			)
+			rec := StateRecord{Kind: StateSession, Session: &Session{}}
+			if err := rows.Scan(&rec.Session.ID, &userId, &data, &expiresAt, &rec.ExpiresAt); err != nil {
+				return rec, err
+			}
+			if err := json.Unmarshal([]byte(data), &rec.Session.Data); err != nil {
+				return rec, fmt.Errorf("解码会话数据失败: %v", err)
+			}
+			rec.Session.UserId = uint(userId)
+			rec.Session.ExpiresAt = time.UnixMilli(expiresAt)
+			return rec, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (s *SQLStore) GetEpoch(ctx context.Context) (int64, error) {
+	var epoch int64
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT value FROM {p}meta WHERE name = ?`), sqlEpochName).Scan(&epoch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("读取全局撤销时间点失败: %v", err)
+	}
+	return epoch, nil
+}
+
+func (s *SQLStore) RaiseEpoch(ctx context.Context, epoch int64) (int64, error) {
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO {p}meta (name, value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = CASE WHEN excluded.value > {p}meta.value THEN excluded.value ELSE {p}meta.value END`),
+		sqlEpochName, epoch)
+	if err != nil {
+		return 0, fmt.Errorf("写入全局撤销时间点失败: %v", err)
+	}
+	return s.GetEpoch(ctx)
+}
+
+func (s *SQLStore) PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (GraceState, bool, error) {
+	// 先清理该键已过期的记录，避免阻塞新的写入
+	if _, err := s.db.ExecContext(ctx, s.query(`DELETE FROM {p}grace WHERE token_key = ? AND expiry > 0 AND expiry <= ?`),
+		key, time.Now().UnixMilli()); err != nil {
+		return GraceState{}, false, err
+	}
+
+	res, err := s.db.ExecContext(ctx, s.query(`INSERT INTO {p}grace (token_key, deadline, new_token_key, expiry) VALUES (?, ?, ?, ?)
+		ON CONFLICT (token_key) DO NOTHING`),
+		key, state.Deadline.UnixMilli(), state.NewTokenKey, expiryMillis(ttl))
+	if err != nil {
+		return GraceState{}, false, err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return state, true, nil
+	}
+	existing, _, err := s.GetGrace(ctx, key)
+	return existing, false, err
+}
+
+func (s *SQLStore) GetGrace(ctx context.Context, key string) (GraceState, bool, error) {
+	var (
+		state    GraceState
+		deadline int64
+	)
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT deadline, new_token_key FROM {p}grace
+		WHERE token_key = ? AND (expiry = 0 OR expiry > ?)`), key, time.Now().UnixMilli()).
+		Scan(&deadline, &state.NewTokenKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		return GraceState{}, false, nil
+	}
+	if err != nil {
+		return GraceState{}, false, err
+	}
+	state.Deadline = time.UnixMilli(deadline)
+	return state, true, nil
+}
+
+func (s *SQLStore) DeleteGrace(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM {p}grace WHERE token_key = ?`), key)
+	return err
+}
+
+func (s *SQLStore) SetSession(ctx context.Context, sess Session, ttl time.Duration) error {
+	if sess.ID == "" {
+		return fmt.Errorf("会话ID不能为空")
+	}
+	data, err := json.Marshal(sess.Data)
+	if err != nil {
+		return fmt.Errorf("编码会话数据失败: %v", err)
+	}
+	_, err = s.db.ExecContext(ctx, s.query(`INSERT INTO {p}sessions (id, user_id, data, expires_at, expiry) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET user_id = excluded.user_id, data = excluded.data,
+		expires_at = excluded.expires_at, expiry = excluded.expiry`),
+		sess.ID, int64(sess.UserId), string(data), sess.ExpiresAt.UnixMilli(), expiryMillis(ttl))
+	return err
+}
+
+func (s *SQLStore) GetSession(ctx context.Context, id string) (Session, bool, error) {
+	var (
+		sess      Session
+		userId    int64
+		data      string
+		expiresAt int64
+	)
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT user_id, data, expires_at FROM {p}sessions
+		WHERE id = ? AND (expiry = 0 OR expiry > ?)`), id, time.Now().UnixMilli()).
+		Scan(&userId, &data, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, err
+	}
+	if err := json.Unmarshal([]byte(data), &sess.Data); err != nil {
+		return Session{}, false, fmt.Errorf("解码会话数据失败: %v", err)
+	}
+	sess.ID = id
+	sess.UserId = uint(userId)
+	sess.ExpiresAt = time.UnixMilli(expiresAt)
+	return sess, true, nil
+}
+
+func (s *SQLStore) DeleteSession(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM {p}sessions WHERE id = ?`), id)
+	return err
+}
+
+func (s *SQLStore) status() StoreStatus {
+	return StoreStatus{Backend: "sql"}
+}
+
+// Sweep 删除所有已过期的记录，返回删除的行数
+func (s *SQLStore) Sweep() (int64, error) {
+	now := time.Now().UnixMilli()
+	var total int64
+	for _, table := range []string{"tokens", "revocations", "grace", "sessions"} {
+		res, err := s.db.Exec(s.query(`DELETE FROM {p}`+table+` WHERE expiry > 0 AND expiry <= ?`), now)
+		if err != nil {
+			return total, fmt.Errorf("清理%s失败: %v", table, err)
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			total += n
+		}
+	}
+	return total, nil
+}
+
+func (s *SQLStore) sweepLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, _ = s.Sweep()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Close 停止后台清理，不关闭传入的*sql.DB
+func (s *SQLStore) Close() error {
+	s.stopOnce.Do(func() {
+		close(s.quit)
+	})
+	<-s.done
+	return nil
+}
@@ -0,0 +1,200 @@
+package gosjwt
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func openTestSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "gosjwt.db"))
+	assert.NoError(t, err)
+	db.SetMaxOpenConns(1) // SQLite单写者
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLStore(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+	store, err := NewSQLStore(db, SQLStoreOptions{SweepInterval: -1})
+	assert.NoError(t, err)
+	defer store.Close()
+
+	// 测试用例1: 迁移可重复执行
+	t.Run("Migrate", func(t *testing.T) {
+		again, err := NewSQLStore(db, SQLStoreOptions{SweepInterval: -1})
+		assert.NoError(t, err)
+		defer again.Close()
+
+		version, err := again.SchemaVersion()
+		assert.NoError(t, err)
+		assert.Equal(t, len(sqlMigrations), version)
+
+		// 键列不限长度，PostgreSQL会校验VARCHAR长度
+		assert.NotContains(t, strings.Join(sqlMigrations[0], "\n"), "VARCHAR(128)")
+	})
+
+	// 测试用例2: Token元数据与撤销记录
+	t.Run("TokensAndRevocations", func(t *testing.T) {
+		rec := TokenRecord{UserId: 42, IssuedAt: 1700000000, ExpiresAt: 1700003600}
+		assert.NoError(t, store.SetToken(ctx, "token-a", rec, time.Minute))
+		got, found, err := store.GetToken(ctx, "token-a")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, rec, got)
+
+		assert.NoError(t, store.Revoke(ctx, "token-a", time.Minute))
+		assert.NoError(t, store.Revoke(ctx, "token-b", 0))
+		revoked, err := store.IsRevoked(ctx, "token-a")
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		snapshot, err := store.Revocations(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, snapshot, 2)
+
+		assert.NoError(t, store.DeleteToken(ctx, "token-a"))
+		_, found, err = store.GetToken(ctx, "token-a")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	// 测试用例3: 全局撤销时间点只升不降
+	t.Run("Epoch", func(t *testing.T) {
+		epoch, err := store.RaiseEpoch(ctx, 200)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), epoch)
+		epoch, err = store.RaiseEpoch(ctx, 100)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), epoch)
+	})
+
+	// 测试用例4: 宽限期状态只写入一次，过期后可重新写入
+	t.Run("Grace", func(t *testing.T) {
+		deadline := time.Now().Add(time.Minute)
+		var (
+			wg   sync.WaitGroup
+			mu   sync.Mutex
+			wins int
+		)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, stored, err := store.PutGrace(ctx, "token-c", GraceState{Deadline: deadline, NewTokenKey: "new"}, time.Minute)
+				assert.NoError(t, err)
+				if stored {
+					mu.Lock()
+					wins++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, wins)
+
+		state, found, err := store.GetGrace(ctx, "token-c")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, deadline.UnixMilli(), state.Deadline.UnixMilli())
+
+		_, stored, err := store.PutGrace(ctx, "token-d", GraceState{Deadline: deadline}, time.Millisecond)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+		time.Sleep(5 * time.Millisecond)
+		_, stored, err = store.PutGrace(ctx, "token-d", GraceState{Deadline: deadline}, time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, stored)
+	})
+
+	// 测试用例5: 会话读写
+	t.Run("Session", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+		sess := Session{ID: "sess-1", UserId: 7, Data: map[string]string{"role": "admin"}, ExpiresAt: expiresAt}
+		assert.NoError(t, store.SetSession(ctx, sess, time.Hour))
+
+		got, found, err := store.GetSession(ctx, "sess-1")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, uint(7), got.UserId)
+		assert.Equal(t, "admin", got.Data["role"])
+		assert.True(t, expiresAt.Equal(got.ExpiresAt))
+
+		assert.NoError(t, store.DeleteSession(ctx, "sess-1"))
+		_, found, err = store.GetSession(ctx, "sess-1")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	// 测试用例6: 清理过期记录
+	t.Run("Sweep", func(t *testing.T) {
+		assert.NoError(t, store.Revoke(ctx, "token-e", time.Millisecond))
+		assert.NoError(t, store.SetToken(ctx, "token-e", TokenRecord{UserId: 1}, time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+
+		revoked, err := store.IsRevoked(ctx, "token-e")
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+
+		removed, err := store.Sweep()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), removed)
+	})
+
+	// 测试用例7: PostgreSQL占位符
+	t.Run("PostgresPlaceholders", func(t *testing.T) {
+		pg := &SQLStore{dialect: SQLDialectPostgres, prefix: "app_"}
+		assert.Equal(t, "SELECT a FROM app_tokens WHERE b = $1 AND c > $2",
+			pg.query("SELECT a FROM {p}tokens WHERE b = ? AND c > ?"))
+	})
+
+	// 测试用例8: 作为处理器存储
+	t.Run("Handler", func(t *testing.T) {
+		handler, err := NewJwtHandler(&Config{
+			SigningKey:      []byte("sql-store-key"),
+			Expires:         3600,
+			LocalRevocation: true,
+			Store:           store,
+		})
+		assert.NoError(t, err)
+
+		token, err := handler.ReleaseToken(9)
+		assert.NoError(t, err)
+		_, claims, err := handler.ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(9), claims.UserId)
+
+		assert.NoError(t, handler.RevokeToken(token))
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+		revoked, err := store.IsRevoked(ctx, handler.TokenKey(token))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+		handler.Close()
+	})
+
+	// 测试用例9: 以原始Token为键的历史记录与较长的会话ID超过128字节
+	t.Run("LegacyRawTokenKey", func(t *testing.T) {
+		raw, err := newTestHandler(t, &Config{Expires: 3600}).ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.Greater(t, len(raw), 128)
+
+		assert.NoError(t, store.Revoke(ctx, raw, time.Minute))
+		revoked, err := store.IsRevoked(ctx, raw)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		assert.NoError(t, store.SetSession(ctx, Session{ID: raw, UserId: 1}, time.Minute))
+		_, found, err := store.GetSession(ctx, raw)
+		assert.NoError(t, err)
+		assert.True(t, found)
+	})
+}
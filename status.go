@@ -0,0 +1,81 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 16:20:14
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 16:20:14
+ * Description: 存储状态
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StoreStatus 存储运行状态
+type StoreStatus struct {
+	Backend   string        `json:"backend"`              // 当前使用的存储："redis"、"memory"、"file"、"sql" 或 "custom"
+	Policy    FailurePolicy `json:"policy,omitempty"`     // Redis不可用时的处理策略
+	Degraded  bool          `json:"degraded"`             // 配置为Redis但当前使用内存缓存，撤销不会跨实例生效
+	LastError string        `json:"last_error,omitempty"` // 最近一次连接Redis或压缩数据文件的错误
+	Since     time.Time     `json:"since"`                // 进入降级或恢复的时间，未发生切换时为零值
+	Retries   uint64        `json:"retries"`              // 后台重连次数
+	Breaker   BreakerState  `json:"breaker,omitempty"`    // 启用熔断时的当前状态
+}
+
+// statusReporter 可报告运行状态的内置存储
+type statusReporter interface {
+	status() StoreStatus
+}
+
+// storeWrapper 包装其他存储的内置存储，如重试与熔断
+type storeWrapper interface {
+	unwrap() Store
+}
+
+// baseStore 返回去掉包装层后的存储
+func baseStore(s Store) Store {
+	for {
+		w, ok := s.(storeWrapper)
+		if !ok {
+			return s
+		}
+		s = w.unwrap()
+	}
+}
+
+// StoreStatus 返回存储运行状态
+func (j *JwtHandler) StoreStatus() StoreStatus {
+	if r, ok := j.store.(statusReporter); ok {
+		return r.status()
+	}
+	return StoreStatus{Backend: "custom"}
+}
+
+// MemoryStats 返回有界内存存储的容量与淘汰指标，启用本地一级缓存时返回其指标，未配置容量上限时返回零值
+func (j *JwtHandler) MemoryStats() MemoryStats {
+	switch s := baseStore(j.store).(type) {
+	case *boundedStore:
+		return s.Stats()
+	case *failoverStore:
+		return s.memoryStats()
+	case *tieredStore:
+		return s.Stats()
+	}
+	return MemoryStats{}
+}
+
+// StatusHandler 存储状态接口，降级时返回503，可用于健康检查
+func (j *JwtHandler) StatusHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := j.StoreStatus()
+		code := http.StatusOK
+		if status.Degraded {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, status)
+	}
+}
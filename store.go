@@ -0,0 +1,124 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 15:02:11
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 15:02:11
+ * Description: 存储接口
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"time"
+)
+
+// TokenRecord Token元数据
+type TokenRecord struct {
+	UserId    uint  `json:"user_id"`
+	IssuedAt  int64 `json:"issued_at"`
+	ExpiresAt int64 `json:"expires_at"`
+}
+
+// GraceState 过期Token的宽限期状态
+type GraceState struct {
+	Deadline    time.Time `json:"deadline"`      // 绝对截止时间
+	NewTokenKey string    `json:"new_token_key"` // 续期签发的新Token的键，不保存原始Token
+}
+
+// Session 会话
+type Session struct {
+	ID        string            `json:"id"`
+	UserId    uint              `json:"user_id"`
+	Data      map[string]string `json:"data,omitempty"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// Store Token存储接口，覆盖Token元数据、撤销记录、宽限期状态与会话
+// 查询不存在的记录返回 found=false 且 err=nil；ttl<=0 表示不过期
+// 实现应在ctx取消或超时后尽快返回，并保留ctx.Err()以便调用方识别超时
+type Store interface {
+	// Token元数据
+	SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error
+	GetToken(ctx context.Context, key string) (rec TokenRecord, found bool, err error)
+	DeleteToken(ctx context.Context, key string) error
+
+	// 撤销记录
+	Revoke(ctx context.Context, key string, ttl time.Duration) error
+	IsRevoked(ctx context.Context, key string) (bool, error)
+
+	// 全局撤销时间点(Unix秒)，只升不降
+	GetEpoch(ctx context.Context) (int64, error)
+	RaiseEpoch(ctx context.Context, epoch int64) (int64, error)
+
+	// 宽限期状态
+	// PutGrace 仅在不存在时写入，返回最终生效的状态及本次是否写入成功，多实例并发时只有一方胜出
+	PutGrace(ctx context.Context, key string, state GraceState, ttl time.Duration) (actual GraceState, stored bool, err error)
+	GetGrace(ctx context.Context, key string) (state GraceState, found bool, err error)
+	DeleteGrace(ctx context.Context, key string) error
+
+	// 会话
+	SetSession(ctx context.Context, s Session, ttl time.Duration) error
+	GetSession(ctx context.Context, id string) (s Session, found bool, err error)
+	DeleteSession(ctx context.Context, id string) error
+
+	Close() error
+}
+
+// RevocationLister 可枚举撤销记录的存储，用于本地撤销集合与布隆过滤器的全量校准
+type RevocationLister interface {
+	// Revocations 返回未过期的撤销记录及其过期时间
+	Revocations(ctx context.Context) (map[string]time.Time, error)
+}
+
+// RevocationEvent 跨实例广播的撤销事件
+type RevocationEvent struct {
+	Type      string `json:"type"`                 // "revoke"、"epoch"，或由存储在订阅重新建立时本地回调的"resync"（期间的事件可能已丢失）
+	Key       string `json:"key,omitempty"`        // 被撤销Token的键
+	ExpiresAt int64  `json:"expires_at,omitempty"` // 撤销记录过期时间(Unix毫秒)
+	Epoch     int64  `json:"epoch,omitempty"`      // 全局撤销时间点(Unix秒)
+}
+
+// RevocationNotifier 支持跨实例广播撤销事件的存储
+type RevocationNotifier interface {
+	PublishRevocation(ctx context.Context, ev RevocationEvent) error
+	// SubscribeRevocations 建立订阅后返回，事件在后台协程中回调，stop用于结束订阅
+	SubscribeRevocations(handle func(ev RevocationEvent)) (stop func(), err error)
+}
+
+// 导出记录类型
+const (
+	StateRevocation = "revocation" // 撤销记录
+	StateGrace      = "grace"      // 宽限期状态
+	StateSession    = "session"    // 会话
+	StateEpoch      = "epoch"      // 全局撤销时间点，仅由处理器写入导出文件
+)
+
+// StateRecord 导出文件中的一行，同一时间只有与Kind对应的字段有值
+type StateRecord struct {
+	Kind      string      `json:"kind"`
+	Key       string      `json:"key,omitempty"`        // 撤销记录与宽限期状态的存储键
+	ExpiresAt int64       `json:"expires_at,omitempty"` // 记录过期时间(Unix毫秒)，0表示不过期
+	Epoch     int64       `json:"epoch,omitempty"`      // 全局撤销时间点(Unix秒)
+	Grace     *GraceState `json:"grace,omitempty"`
+	Session   *Session    `json:"session,omitempty"`
+}
+
+// StateExporter 可枚举撤销记录、宽限期状态与会话的存储，用于备份与迁移
+type StateExporter interface {
+	// ExportState 返回未过期的记录，不过期的记录ExpiresAt为0，不包含全局撤销时间点
+	ExportState(ctx context.Context) ([]StateRecord, error)
+}
+
+// NewStore 按缓存配置创建内置存储：Redis、内存或单机文件
+func NewStore(cfg CacheConfig) (Store, error) {
+	return newCacheStore(cfg)
+}
+
+// expiryMillis 将ttl转换为绝对过期时间(Unix毫秒)，ttl<=0表示不过期并返回0，各内置存储共用
+func expiryMillis(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(ttl).UnixMilli()
+}
@@ -0,0 +1,211 @@
+package gosjwt
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	backends := map[string]CacheConfig{
+		"memory": {Type: "memory"},
+		"redis":  {Type: "redis", RedisAddr: mr.Addr(), Prefix: "store_"},
+	}
+
+	for name, cfg := range backends {
+		t.Run(name, func(t *testing.T) {
+			store, err := NewStore(cfg)
+			assert.NoError(t, err)
+			defer store.Close()
+
+			// 测试用例1: 撤销记录
+			t.Run("Revoke", func(t *testing.T) {
+				revoked, err := store.IsRevoked(ctx, "token-a")
+				assert.NoError(t, err)
+				assert.False(t, revoked)
+
+				assert.NoError(t, store.Revoke(ctx, "token-a", time.Minute))
+				revoked, err = store.IsRevoked(ctx, "token-a")
+				assert.NoError(t, err)
+				assert.True(t, revoked)
+			})
+
+			// 测试用例2: 全局撤销时间点只升不降
+			t.Run("Epoch", func(t *testing.T) {
+				epoch, err := store.RaiseEpoch(ctx, 200)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(200), epoch)
+
+				epoch, err = store.RaiseEpoch(ctx, 100)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(200), epoch)
+
+				epoch, err = store.GetEpoch(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(200), epoch)
+			})
+
+			// 测试用例3: 并发写入宽限期状态时只有一方胜出
+			t.Run("PutGraceOnce", func(t *testing.T) {
+				deadline := time.Now().Add(time.Minute).Truncate(time.Second)
+				var (
+					wg     sync.WaitGroup
+					mu     sync.Mutex
+					wins   int
+					actual []GraceState
+				)
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						state := GraceState{Deadline: deadline, NewTokenKey: string(rune('a' + i))}
+						got, stored, err := store.PutGrace(ctx, "token-b", state, time.Minute)
+						assert.NoError(t, err)
+						mu.Lock()
+						defer mu.Unlock()
+						if stored {
+							wins++
+						}
+						actual = append(actual, got)
+					}(i)
+				}
+				wg.Wait()
+
+				assert.Equal(t, 1, wins)
+				for _, got := range actual {
+					assert.Equal(t, actual[0].NewTokenKey, got.NewTokenKey)
+					assert.True(t, deadline.Equal(got.Deadline))
+				}
+
+				assert.NoError(t, store.DeleteGrace(ctx, "token-b"))
+				_, found, err := store.GetGrace(ctx, "token-b")
+				assert.NoError(t, err)
+				assert.False(t, found)
+			})
+
+			// 测试用例4: 会话读写
+			t.Run("Session", func(t *testing.T) {
+				sess := Session{ID: "sess-1", UserId: 7, Data: map[string]string{"role": "admin"}}
+				assert.NoError(t, store.SetSession(ctx, sess, time.Minute))
+
+				got, found, err := store.GetSession(ctx, "sess-1")
+				assert.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, uint(7), got.UserId)
+				assert.Equal(t, "admin", got.Data["role"])
+
+				assert.NoError(t, store.DeleteSession(ctx, "sess-1"))
+				_, found, err = store.GetSession(ctx, "sess-1")
+				assert.NoError(t, err)
+				assert.False(t, found)
+
+				assert.Error(t, store.SetSession(ctx, Session{}, time.Minute))
+			})
+		})
+	}
+
+	// 测试用例5: Redis存储可枚举撤销记录，不包含全局撤销时间点
+	t.Run("RedisRevocations", func(t *testing.T) {
+		store, err := newRedisStore(CacheConfig{Type: "redis", RedisAddr: mr.Addr(), Prefix: "list_"})
+		assert.NoError(t, err)
+		defer store.Close()
+
+		assert.NoError(t, store.Revoke(ctx, "token-c", time.Minute))
+		_, err = store.RaiseEpoch(ctx, 100)
+		assert.NoError(t, err)
+
+		snapshot, err := store.Revocations(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, snapshot, 1)
+		assert.Contains(t, snapshot, "token-c")
+	})
+
+	// 测试用例6: 使用自定义存储
+	t.Run("CustomStore", func(t *testing.T) {
+		inner, err := newMemoryStore()
+		assert.NoError(t, err)
+		counter := &lookupCounter{Store: inner}
+
+		handler, err := NewJwtHandler(&Config{
+			SigningKey: []byte("custom-store-key"),
+			Expires:    3600,
+			Store:      counter,
+		})
+		assert.NoError(t, err)
+		defer handler.Close()
+		assert.Same(t, counter, handler.Store())
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		_, _, err = handler.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+		assert.Equal(t, 1, counter.lookups)
+	})
+}
+
+func TestTokenCache(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	backends := map[string]CacheConfig{
+		"memory": {Type: "memory"},
+		"redis":  {Type: "redis", RedisAddr: mr.Addr(), Prefix: "token_cache_"},
+	}
+
+	for name, cfg := range backends {
+		t.Run(name, func(t *testing.T) {
+			// 测试用例1: 记录按原类型往返，超出float64精度的用户ID不丢失
+			t.Run("RoundTrip", func(t *testing.T) {
+				store, err := NewStore(cfg)
+				assert.NoError(t, err)
+				defer store.Close()
+
+				rec := TokenRecord{UserId: 1<<53 + 1, IssuedAt: 1700000000, ExpiresAt: 1700003600}
+				assert.NoError(t, store.SetToken(ctx, "token-a", rec, time.Minute))
+
+				got, found, err := store.GetToken(ctx, "token-a")
+				assert.NoError(t, err)
+				assert.True(t, found)
+				assert.Equal(t, rec, got)
+
+				assert.NoError(t, store.DeleteToken(ctx, "token-a"))
+				_, found, err = store.GetToken(ctx, "token-a")
+				assert.NoError(t, err)
+				assert.False(t, found)
+			})
+
+			// 测试用例2: 解析时命中缓存，不再验证签名
+			t.Run("ParseHitsCache", func(t *testing.T) {
+				handler, err := NewJwtHandler(&Config{
+					SigningKey: []byte("token-cache-key"),
+					Issuer:     "test-issuer",
+					Expires:    3600,
+					Cache:      cfg,
+				})
+				assert.NoError(t, err)
+				defer handler.Close()
+
+				token, err := handler.ReleaseToken(42)
+				assert.NoError(t, err)
+
+				// 更换密钥后仍能解析，说明结果来自缓存
+				handler.Config.SigningKey = []byte("rotated-key")
+				_, claims, err := handler.ParseToken(token)
+				assert.NoError(t, err)
+				assert.Equal(t, uint(42), claims.UserId)
+				assert.Equal(t, "test-issuer", claims.Issuer)
+
+				// 删除缓存后回落到签名验证
+				assert.NoError(t, handler.store.DeleteToken(ctx, handler.TokenKey(token)))
+				_, _, err = handler.ParseToken(token)
+				assert.Error(t, err)
+			})
+		})
+	}
+}
@@ -0,0 +1,526 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 21:15:40
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 21:15:40
+ * Description: 存储实现的一致性测试套件
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+
+// Package storetest 校验gosjwt.Store实现是否符合接口约定
+//
+// 第三方存储在自己的测试中调用Run即可：
+//
+//	func TestMyStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) gosjwt.Store {
+//			return newMyStore(t)
+//		}, storetest.Options{})
+//	}
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	gosjwt "github.com/zjguoxin/gos-jwt"
+)
+
+// Factory 为每个用例创建一个空存储，用例结束时由套件关闭
+type Factory func(t *testing.T) gosjwt.Store
+
+// Options 套件配置
+type Options struct {
+	// Advance 让存储感知到时间流逝，默认time.Sleep；使用模拟时钟的后端（如miniredis）传入FastForward
+	Advance func(d time.Duration)
+	// Concurrency 并发用例的协程数，默认16
+	Concurrency int
+}
+
+// shortTTL 过期用例使用的时长，推进时钟时多留出余量
+const shortTTL = 50 * time.Millisecond
+
+// Run 运行全部用例：Token元数据、TTL过期、撤销记录、全局撤销时间点、宽限期原子写入、会话与并发访问；
+// 存储实现了RevocationLister、RevocationNotifier或StateExporter时一并校验
+//
+// 套件只依赖标准库testing，第三方存储无需引入额外的断言库
+func Run(t *testing.T, newStore Factory, opts Options) {
+	if opts.Advance == nil {
+		opts.Advance = time.Sleep
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 16
+	}
+	open := func(t *testing.T) gosjwt.Store {
+		s := newStore(t)
+		if s == nil {
+			t.Fatal("Factory返回了nil存储")
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		return s
+	}
+	ctx := context.Background()
+
+	t.Run("Tokens", func(t *testing.T) {
+		s := open(t)
+		rec := gosjwt.TokenRecord{UserId: 42, IssuedAt: 1700000000, ExpiresAt: 1700003600}
+
+		_, found, err := s.GetToken(ctx, "missing")
+		if err != nil {
+			t.Errorf("查询不存在的记录不应返回错误: %v", err)
+		}
+		if found {
+			t.Error("不存在的记录不应被找到")
+		}
+
+		mustNoError(t, s.SetToken(ctx, "token-a", rec, time.Minute))
+		got, found, err := s.GetToken(ctx, "token-a")
+		mustNoError(t, err)
+		if !found || !reflect.DeepEqual(rec, got) {
+			t.Errorf("GetToken = %+v, %v; 期望 %+v, true", got, found, rec)
+		}
+
+		rec.UserId = 43
+		mustNoError(t, s.SetToken(ctx, "token-a", rec, time.Minute))
+		got, _, err = s.GetToken(ctx, "token-a")
+		mustNoError(t, err)
+		if got.UserId != 43 {
+			t.Errorf("重复写入应覆盖: UserId = %d, 期望 43", got.UserId)
+		}
+
+		mustNoError(t, s.DeleteToken(ctx, "token-a"))
+		_, found, err = s.GetToken(ctx, "token-a")
+		if err != nil || found {
+			t.Errorf("删除后GetToken = %v, %v; 期望 false, nil", found, err)
+		}
+		if err := s.DeleteToken(ctx, "token-a"); err != nil {
+			t.Errorf("删除不存在的记录不应返回错误: %v", err)
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		s := open(t)
+		now := time.Now().Truncate(time.Millisecond)
+		rec := gosjwt.TokenRecord{UserId: 1}
+		grace := gosjwt.GraceState{Deadline: now.Add(time.Minute), NewTokenKey: "new"}
+		sess := gosjwt.Session{ID: "sess", UserId: 1, ExpiresAt: now.Add(time.Minute)}
+
+		mustNoError(t, s.SetToken(ctx, "short", rec, shortTTL))
+		mustNoError(t, s.SetToken(ctx, "forever", rec, 0))
+		mustNoError(t, s.Revoke(ctx, "short", shortTTL))
+		mustNoError(t, s.Revoke(ctx, "forever", 0))
+		_, _, err := s.PutGrace(ctx, "short", grace, shortTTL)
+		mustNoError(t, err)
+		mustNoError(t, s.SetSession(ctx, sess, shortTTL))
+		sess.ID = "sess-forever"
+		mustNoError(t, s.SetSession(ctx, sess, 0))
+
+		opts.Advance(3 * shortTTL)
+
+		_, found, err := s.GetToken(ctx, "short")
+		if err != nil || found {
+			t.Errorf("Token元数据应按TTL过期: found = %v, err = %v", found, err)
+		}
+		if _, found, _ = s.GetToken(ctx, "forever"); !found {
+			t.Error("ttl<=0表示不过期: Token元数据丢失")
+		}
+
+		revoked, err := s.IsRevoked(ctx, "short")
+		if err != nil || revoked {
+			t.Errorf("撤销记录应按TTL过期: revoked = %v, err = %v", revoked, err)
+		}
+		if revoked, _ = s.IsRevoked(ctx, "forever"); !revoked {
+			t.Error("ttl<=0表示不过期: 撤销记录丢失")
+		}
+
+		_, found, err = s.GetGrace(ctx, "short")
+		if err != nil || found {
+			t.Errorf("宽限期状态应按TTL过期: found = %v, err = %v", found, err)
+		}
+		_, stored, err := s.PutGrace(ctx, "short", grace, time.Minute)
+		if err != nil || !stored {
+			t.Errorf("过期的宽限期状态不应阻止新的写入: stored = %v, err = %v", stored, err)
+		}
+
+		_, found, err = s.GetSession(ctx, "sess")
+		if err != nil || found {
+			t.Errorf("会话应按TTL过期: found = %v, err = %v", found, err)
+		}
+		if _, found, _ = s.GetSession(ctx, "sess-forever"); !found {
+			t.Error("ttl<=0表示不过期: 会话丢失")
+		}
+	})
+
+	t.Run("Revocation", func(t *testing.T) {
+		s := open(t)
+		revoked, err := s.IsRevoked(ctx, "token-a")
+		if err != nil || revoked {
+			t.Errorf("IsRevoked = %v, %v; 期望 false, nil", revoked, err)
+		}
+
+		mustNoError(t, s.Revoke(ctx, "token-a", time.Minute))
+		revoked, err = s.IsRevoked(ctx, "token-a")
+		if err != nil || !revoked {
+			t.Errorf("IsRevoked = %v, %v; 期望 true, nil", revoked, err)
+		}
+		if revoked, _ = s.IsRevoked(ctx, "token-b"); revoked {
+			t.Error("撤销不应影响其他键")
+		}
+
+		// 重复撤销延长保留时间
+		mustNoError(t, s.Revoke(ctx, "token-c", shortTTL))
+		mustNoError(t, s.Revoke(ctx, "token-c", time.Minute))
+		opts.Advance(3 * shortTTL)
+		if revoked, _ = s.IsRevoked(ctx, "token-c"); !revoked {
+			t.Error("重复撤销应以最后一次的TTL为准")
+		}
+	})
+
+	t.Run("Epoch", func(t *testing.T) {
+		s := open(t)
+		epoch, err := s.GetEpoch(ctx)
+		mustNoError(t, err)
+		if epoch != 0 {
+			t.Errorf("未设置时应为0: GetEpoch = %d", epoch)
+		}
+
+		current, err := s.RaiseEpoch(ctx, 1700000000)
+		mustNoError(t, err)
+		if current != 1700000000 {
+			t.Errorf("RaiseEpoch = %d, 期望 1700000000", current)
+		}
+		current, err = s.RaiseEpoch(ctx, 1600000000)
+		mustNoError(t, err)
+		if current != 1700000000 {
+			t.Errorf("全局撤销时间点只升不降: RaiseEpoch = %d, 期望 1700000000", current)
+		}
+		if epoch, _ = s.GetEpoch(ctx); epoch != 1700000000 {
+			t.Errorf("GetEpoch = %d, 期望 1700000000", epoch)
+		}
+
+		// 全局撤销时间点不应被当作撤销记录或因时间推进而过期
+		opts.Advance(3 * shortTTL)
+		if epoch, _ = s.GetEpoch(ctx); epoch != 1700000000 {
+			t.Errorf("全局撤销时间点不应过期: GetEpoch = %d", epoch)
+		}
+	})
+
+	t.Run("Grace", func(t *testing.T) {
+		s := open(t)
+		deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+		first := gosjwt.GraceState{Deadline: deadline, NewTokenKey: "first"}
+		second := gosjwt.GraceState{Deadline: deadline.Add(time.Second), NewTokenKey: "second"}
+
+		_, found, err := s.GetGrace(ctx, "expired")
+		if err != nil || found {
+			t.Errorf("GetGrace = %v, %v; 期望 false, nil", found, err)
+		}
+
+		actual, stored, err := s.PutGrace(ctx, "expired", first, time.Minute)
+		mustNoError(t, err)
+		if !stored {
+			t.Error("首次写入应成功")
+		}
+		checkGrace(t, first, actual)
+
+		actual, stored, err = s.PutGrace(ctx, "expired", second, time.Minute)
+		mustNoError(t, err)
+		if stored {
+			t.Error("已存在时不应写入")
+		}
+		checkGrace(t, first, actual)
+
+		got, found, err := s.GetGrace(ctx, "expired")
+		mustNoError(t, err)
+		if !found {
+			t.Error("宽限期状态未找到")
+		}
+		checkGrace(t, first, got)
+
+		mustNoError(t, s.DeleteGrace(ctx, "expired"))
+		actual, stored, err = s.PutGrace(ctx, "expired", second, time.Minute)
+		mustNoError(t, err)
+		if !stored {
+			t.Error("删除后应可重新写入")
+		}
+		checkGrace(t, second, actual)
+	})
+
+	t.Run("GraceAtomicity", func(t *testing.T) {
+		s := open(t)
+		deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+
+		type result struct {
+			actual gosjwt.GraceState
+			stored bool
+			err    error
+		}
+		results := make([]result, opts.Concurrency)
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				state := gosjwt.GraceState{Deadline: deadline, NewTokenKey: fmt.Sprint("new-", i)}
+				actual, stored, err := s.PutGrace(ctx, "contended", state, time.Minute)
+				results[i] = result{actual, stored, err}
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		winners := 0
+		var winner string
+		for i, r := range results {
+			mustNoError(t, r.err)
+			if r.stored {
+				winners++
+				winner = fmt.Sprint("new-", i)
+			}
+		}
+		if winners != 1 {
+			t.Fatalf("并发写入时只能有一方成功: %d方写入成功", winners)
+		}
+		for _, r := range results {
+			if r.actual.NewTokenKey != winner {
+				t.Errorf("所有调用方都应得到胜出的状态: %q != %q", r.actual.NewTokenKey, winner)
+			}
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		s := open(t)
+		sess := gosjwt.Session{
+			ID:        "sess-1",
+			UserId:    7,
+			Data:      map[string]string{"role": "admin"},
+			ExpiresAt: time.Now().Add(time.Hour).Truncate(time.Millisecond),
+		}
+
+		_, found, err := s.GetSession(ctx, sess.ID)
+		if err != nil || found {
+			t.Errorf("GetSession = %v, %v; 期望 false, nil", found, err)
+		}
+
+		mustNoError(t, s.SetSession(ctx, sess, time.Hour))
+		got, found, err := s.GetSession(ctx, sess.ID)
+		mustNoError(t, err)
+		if !found {
+			t.Fatal("会话未找到")
+		}
+		if got.ID != sess.ID || got.UserId != sess.UserId || !reflect.DeepEqual(got.Data, sess.Data) {
+			t.Errorf("GetSession = %+v, 期望 %+v", got, sess)
+		}
+		if !sess.ExpiresAt.Equal(got.ExpiresAt) {
+			t.Errorf("ExpiresAt应至少保留毫秒精度: %v != %v", got.ExpiresAt, sess.ExpiresAt)
+		}
+
+		sess.Data["role"] = "user"
+		mustNoError(t, s.SetSession(ctx, sess, time.Hour))
+		if got, _, _ = s.GetSession(ctx, sess.ID); got.Data["role"] != "user" {
+			t.Errorf("重复写入应覆盖: role = %q", got.Data["role"])
+		}
+
+		mustNoError(t, s.DeleteSession(ctx, sess.ID))
+		_, found, err = s.GetSession(ctx, sess.ID)
+		if err != nil || found {
+			t.Errorf("删除后GetSession = %v, %v; 期望 false, nil", found, err)
+		}
+
+		if err := s.SetSession(ctx, gosjwt.Session{}, time.Hour); err == nil {
+			t.Error("空会话ID应返回错误")
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		s := open(t)
+		var wg sync.WaitGroup
+		errs := make(chan error, opts.Concurrency*5)
+		for i := 0; i < opts.Concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				key := fmt.Sprint("token-", i)
+				errs <- s.SetToken(ctx, key, gosjwt.TokenRecord{UserId: uint(i)}, time.Minute)
+				errs <- s.Revoke(ctx, key, time.Minute)
+				_, err := s.RaiseEpoch(ctx, int64(1700000000+i))
+				errs <- err
+				errs <- s.SetSession(ctx, gosjwt.Session{ID: key, UserId: uint(i)}, time.Minute)
+				if _, _, err := s.GetToken(ctx, key); err != nil {
+					errs <- err
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			mustNoError(t, err)
+		}
+
+		for i := 0; i < opts.Concurrency; i++ {
+			key := fmt.Sprint("token-", i)
+			rec, found, err := s.GetToken(ctx, key)
+			mustNoError(t, err)
+			if !found || rec.UserId != uint(i) {
+				t.Errorf("%s: GetToken = %+v, %v; 期望 UserId %d", key, rec, found, i)
+			}
+			if revoked, _ := s.IsRevoked(ctx, key); !revoked {
+				t.Errorf("%s: 撤销记录丢失", key)
+			}
+		}
+		if epoch, _ := s.GetEpoch(ctx); epoch != int64(1700000000+opts.Concurrency-1) {
+			t.Errorf("并发提高时应保留最大值: GetEpoch = %d", epoch)
+		}
+	})
+
+	t.Run("RevocationLister", func(t *testing.T) {
+		s := open(t)
+		lister, ok := s.(gosjwt.RevocationLister)
+		if !ok {
+			t.Skip("存储未实现RevocationLister")
+		}
+		mustNoError(t, s.Revoke(ctx, "token-a", time.Minute))
+		mustNoError(t, s.Revoke(ctx, "token-b", shortTTL))
+		_, err := s.RaiseEpoch(ctx, 1700000000)
+		mustNoError(t, err)
+		opts.Advance(3 * shortTTL)
+
+		snapshot, err := lister.Revocations(ctx)
+		mustNoError(t, err)
+		if _, ok := snapshot["token-b"]; ok {
+			t.Error("快照不应包含已过期的撤销记录")
+		}
+		if len(snapshot) != 1 {
+			t.Errorf("快照不应包含全局撤销时间点: %v", snapshot)
+		}
+		if expiresAt, ok := snapshot["token-a"]; !ok {
+			t.Error("快照缺少token-a")
+		} else {
+			checkWithin(t, time.Now().Add(time.Minute), expiresAt, "token-a的过期时间")
+		}
+	})
+
+	t.Run("StateExporter", func(t *testing.T) {
+		s := open(t)
+		exporter, ok := s.(gosjwt.StateExporter)
+		if !ok {
+			t.Skip("存储未实现StateExporter")
+		}
+		deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+		sess := gosjwt.Session{ID: "sess-1", UserId: 7, Data: map[string]string{"role": "admin"}, ExpiresAt: deadline}
+
+		mustNoError(t, s.Revoke(ctx, "token-a", time.Minute))
+		mustNoError(t, s.Revoke(ctx, "token-b", 0))
+		mustNoError(t, s.Revoke(ctx, "token-c", shortTTL))
+		_, _, err := s.PutGrace(ctx, "token-d", gosjwt.GraceState{Deadline: deadline, NewTokenKey: "token-e"}, time.Minute)
+		mustNoError(t, err)
+		mustNoError(t, s.SetSession(ctx, sess, time.Hour))
+		mustNoError(t, s.SetSession(ctx, gosjwt.Session{ID: "sess-2"}, shortTTL))
+		mustNoError(t, s.SetSession(ctx, gosjwt.Session{ID: "sess-3"}, time.Hour))
+		mustNoError(t, s.DeleteSession(ctx, "sess-3"))
+		mustNoError(t, s.SetToken(ctx, "token-a", gosjwt.TokenRecord{UserId: 1}, time.Minute))
+		_, err = s.RaiseEpoch(ctx, 1700000000)
+		mustNoError(t, err)
+		opts.Advance(3 * shortTTL)
+
+		records, err := exporter.ExportState(ctx)
+		mustNoError(t, err)
+		byID := make(map[string]gosjwt.StateRecord)
+		for _, rec := range records {
+			id := rec.Kind + ":" + rec.Key
+			if rec.Session != nil {
+				id = rec.Kind + ":" + rec.Session.ID
+			}
+			byID[id] = rec
+		}
+		if len(byID) != 4 {
+			t.Errorf("只导出未过期的撤销记录、宽限期状态与会话，不包含Token元数据与全局撤销时间点: 导出%d项", len(byID))
+		}
+
+		now := time.Now()
+		if rec, ok := byID["revocation:token-a"]; !ok {
+			t.Error("缺少revocation:token-a")
+		} else {
+			checkWithin(t, now.Add(time.Minute), time.UnixMilli(rec.ExpiresAt), "revocation:token-a的过期时间")
+		}
+		if rec, ok := byID["revocation:token-b"]; !ok {
+			t.Error("缺少revocation:token-b")
+		} else if rec.ExpiresAt != 0 {
+			t.Errorf("不过期的记录ExpiresAt应为0: %d", rec.ExpiresAt)
+		}
+		if rec, ok := byID["grace:token-d"]; !ok || rec.Grace == nil {
+			t.Error("缺少grace:token-d")
+		} else {
+			checkGrace(t, gosjwt.GraceState{Deadline: deadline, NewTokenKey: "token-e"}, *rec.Grace)
+			checkWithin(t, now.Add(time.Minute), time.UnixMilli(rec.ExpiresAt), "grace:token-d的过期时间")
+		}
+		if rec, ok := byID["session:sess-1"]; !ok || rec.Session == nil {
+			t.Error("缺少session:sess-1")
+		} else {
+			if rec.Session.UserId != sess.UserId || !reflect.DeepEqual(rec.Session.Data, sess.Data) || !sess.ExpiresAt.Equal(rec.Session.ExpiresAt) {
+				t.Errorf("导出的会话 = %+v, 期望 %+v", *rec.Session, sess)
+			}
+			checkWithin(t, now.Add(time.Hour), time.UnixMilli(rec.ExpiresAt), "session:sess-1的过期时间")
+		}
+	})
+
+	t.Run("RevocationNotifier", func(t *testing.T) {
+		s := open(t)
+		notifier, ok := s.(gosjwt.RevocationNotifier)
+		if !ok {
+			t.Skip("存储未实现RevocationNotifier")
+		}
+		received := make(chan gosjwt.RevocationEvent, 1)
+		stop, err := notifier.SubscribeRevocations(func(ev gosjwt.RevocationEvent) {
+			select {
+			case received <- ev:
+			default:
+			}
+		})
+		mustNoError(t, err)
+		defer stop()
+
+		ev := gosjwt.RevocationEvent{Type: "revoke", Key: "token-a", ExpiresAt: time.Now().Add(time.Minute).UnixMilli()}
+		mustNoError(t, notifier.PublishRevocation(ctx, ev))
+		select {
+		case got := <-received:
+			if got != ev {
+				t.Errorf("收到的撤销事件 = %+v, 期望 %+v", got, ev)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("未收到广播的撤销事件")
+		}
+	})
+}
+
+// mustNoError 出错时终止当前用例
+func mustNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("意外的错误: %v", err)
+	}
+}
+
+// checkGrace 比较宽限期状态，截止时间按时刻比较
+func checkGrace(t *testing.T, expected, actual gosjwt.GraceState) {
+	t.Helper()
+	if actual.NewTokenKey != expected.NewTokenKey {
+		t.Errorf("NewTokenKey = %q, 期望 %q", actual.NewTokenKey, expected.NewTokenKey)
+	}
+	if !expected.Deadline.Equal(actual.Deadline) {
+		t.Errorf("截止时间应至少保留毫秒精度: %v != %v", expected.Deadline, actual.Deadline)
+	}
+}
+
+// checkWithin 检查时间与期望值相差不超过5秒
+func checkWithin(t *testing.T, expected, actual time.Time, what string) {
+	t.Helper()
+	if d := actual.Sub(expected); d < -5*time.Second || d > 5*time.Second {
+		t.Errorf("%s = %v, 期望约为 %v", what, actual, expected)
+	}
+}
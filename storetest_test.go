@@ -0,0 +1,72 @@
+package gosjwt_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+	gosjwt "github.com/zjguoxin/gos-jwt"
+	"github.com/zjguoxin/gos-jwt/storetest"
+	_ "modernc.org/sqlite"
+)
+
+// 内置存储均需通过一致性测试
+func TestStoreConformance(t *testing.T) {
+	// 测试用例1: 内存存储
+	t.Run("Memory", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) gosjwt.Store {
+			s, err := gosjwt.NewStore(gosjwt.CacheConfig{Type: "memory"})
+			require.NoError(t, err)
+			return s
+		}, storetest.Options{})
+	})
+
+	// 测试用例2: 有界内存存储
+	t.Run("BoundedMemory", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) gosjwt.Store {
+			s, err := gosjwt.NewStore(gosjwt.CacheConfig{Type: "memory", MaxEntries: 1000, RevocationCapacity: 1000})
+			require.NoError(t, err)
+			return s
+		}, storetest.Options{})
+	})
+
+	// 测试用例3: Redis存储，使用miniredis并通过FastForward推进时钟
+	t.Run("Redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		storetest.Run(t, func(t *testing.T) gosjwt.Store {
+			mr.FlushAll()
+			s, err := gosjwt.NewStore(gosjwt.CacheConfig{
+				Type:          "redis",
+				RedisAddr:     mr.Addr(),
+				Prefix:        "conformance_",
+				FailurePolicy: gosjwt.FailureFailClosed,
+			})
+			require.NoError(t, err)
+			return s
+		}, storetest.Options{Advance: mr.FastForward})
+	})
+
+	// 测试用例4: 文件存储
+	t.Run("File", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) gosjwt.Store {
+			s, err := gosjwt.NewFileStore(filepath.Join(t.TempDir(), "gosjwt.log"))
+			require.NoError(t, err)
+			return s
+		}, storetest.Options{})
+	})
+
+	// 测试用例5: SQL存储，每个用例使用独立的SQLite数据库
+	t.Run("SQL", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) gosjwt.Store {
+			db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "gosjwt.db"))
+			require.NoError(t, err)
+			db.SetMaxOpenConns(1) // SQLite单写者
+			t.Cleanup(func() { db.Close() })
+			s, err := gosjwt.NewSQLStore(db, gosjwt.SQLStoreOptions{SweepInterval: -1})
+			require.NoError(t, err)
+			return s
+		}, storetest.Options{})
+	})
+}
@@ -0,0 +1,201 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 19:10:27
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 19:10:27
+ * Description: Redis前的本地一级缓存
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultLocalCacheSize 本地一级缓存的默认最大条数
+const defaultLocalCacheSize = 10000
+
+const (
+	tieredKindToken   = "l1token"
+	tieredKindRevoked = "l1revoked"
+)
+
+// tieredToken 本地缓存的Token查询结果，未命中也会缓存
+type tieredToken struct {
+	Rec   TokenRecord `json:"rec"`
+	Found bool        `json:"found"`
+}
+
+// tieredStore 在Redis(L2)前增加短时效的本地缓存(L1)
+// 仅缓存Token元数据与撤销检查结果；撤销与删除Token时广播invalidate事件，
+// 各实例收到撤销或invalidate事件后立即清除本地记录，广播不可达时过期时间即最大延迟
+type tieredStore struct {
+	Store
+	l1      *boundedStore
+	ttl     time.Duration
+	gen     uint64 // 每次失效加一，查询L2期间发生失效时不回填L1
+	stopSub func()
+}
+
+func newTieredStore(cfg CacheConfig, l2 Store) *tieredStore {
+	size := cfg.LocalCacheSize
+	if size <= 0 {
+		size = defaultLocalCacheSize
+	}
+	s := &tieredStore{
+		Store: l2,
+		l1:    newBoundedStore(CacheConfig{MaxEntries: size}),
+		ttl:   time.Duration(cfg.LocalCacheTTL) * time.Millisecond,
+	}
+	// 订阅失败时依赖本地缓存过期
+	if n, ok := l2.(RevocationNotifier); ok {
+		if stop, err := n.SubscribeRevocations(s.applyEvent); err == nil {
+			s.stopSub = stop
+		}
+	}
+	return s
+}
+
+// localTTL 本地缓存时长不超过记录本身的剩余时间
+func (s *tieredStore) localTTL(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < s.ttl {
+		return ttl
+	}
+	return s.ttl
+}
+
+// invalidate 清除本地记录
+func (s *tieredStore) invalidate(key string) {
+	atomic.AddUint64(&s.gen, 1)
+	s.l1.del(tieredKindToken, key)
+	s.l1.del(tieredKindRevoked, key)
+}
+
+// applyEvent 处理本实例及其他实例广播的撤销事件
+func (s *tieredStore) applyEvent(ev RevocationEvent) {
+	switch ev.Type {
+	case "revoke", "invalidate":
+		s.invalidate(ev.Key)
+	}
+}
+
+// publishInvalidate 通知其他实例清除本地记录
+func (s *tieredStore) publishInvalidate(ctx context.Context, key string) {
+	if n, ok := s.Store.(RevocationNotifier); ok {
+		_ = n.PublishRevocation(ctx, RevocationEvent{Type: "invalidate", Key: key})
+	}
+}
+
+func (s *tieredStore) SetToken(ctx context.Context, key string, rec TokenRecord, ttl time.Duration) error {
+	if err := s.Store.SetToken(ctx, key, rec, ttl); err != nil {
+		return err
+	}
+	s.invalidate(key)
+	_ = s.l1.set(tieredKindToken, key, tieredToken{Rec: rec, Found: true}, s.localTTL(ttl))
+	return nil
+}
+
+func (s *tieredStore) GetToken(ctx context.Context, key string) (TokenRecord, bool, error) {
+	var cached tieredToken
+	if found, err := s.l1.lookup(tieredKindToken, key, &cached); err == nil && found {
+		return cached.Rec, cached.Found, nil
+	}
+
+	gen := atomic.LoadUint64(&s.gen)
+	rec, found, err := s.Store.GetToken(ctx, key)
+	if err != nil {
+		return rec, found, err
+	}
+	if atomic.LoadUint64(&s.gen) == gen {
+		_ = s.l1.set(tieredKindToken, key, tieredToken{Rec: rec, Found: found}, s.ttl)
+	}
+	return rec, found, nil
+}
+
+func (s *tieredStore) DeleteToken(ctx context.Context, key string) error {
+	if err := s.Store.DeleteToken(ctx, key); err != nil {
+		return err
+	}
+	s.invalidate(key)
+	s.publishInvalidate(ctx, key)
+	return nil
+}
+
+func (s *tieredStore) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	if err := s.Store.Revoke(ctx, key, ttl); err != nil {
+		return err
+	}
+	s.invalidate(key)
+	// 撤销记录不会被撤回，可按其完整有效期缓存
+	_ = s.l1.set(tieredKindRevoked, key, true, ttl)
+	s.publishInvalidate(ctx, key)
+	return nil
+}
+
+func (s *tieredStore) IsRevoked(ctx context.Context, key string) (bool, error) {
+	var revoked bool
+	if found, err := s.l1.lookup(tieredKindRevoked, key, &revoked); err == nil && found {
+		return revoked, nil
+	}
+
+	gen := atomic.LoadUint64(&s.gen)
+	revoked, err := s.Store.IsRevoked(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	// 已撤销的结果不会过时，总是回填
+	if revoked || atomic.LoadUint64(&s.gen) == gen {
+		_ = s.l1.set(tieredKindRevoked, key, revoked, s.ttl)
+	}
+	return revoked, nil
+}
+
+// Stats 返回本地缓存的容量与淘汰指标
+func (s *tieredStore) Stats() MemoryStats {
+	return s.l1.Stats()
+}
+
+func (s *tieredStore) Revocations(ctx context.Context) (map[string]time.Time, error) {
+	if lister, ok := s.Store.(RevocationLister); ok {
+		return lister.Revocations(ctx)
+	}
+	return nil, nil
+}
+
+// ExportState 直接导出Redis中的记录
+func (s *tieredStore) ExportState(ctx context.Context) ([]StateRecord, error) {
+	if exporter, ok := s.Store.(StateExporter); ok {
+		return exporter.ExportState(ctx)
+	}
+	return nil, ErrExportUnsupported
+}
+
+func (s *tieredStore) PublishRevocation(ctx context.Context, ev RevocationEvent) error {
+	if n, ok := s.Store.(RevocationNotifier); ok {
+		return n.PublishRevocation(ctx, ev)
+	}
+	return nil
+}
+
+func (s *tieredStore) SubscribeRevocations(handle func(ev RevocationEvent)) (func(), error) {
+	if n, ok := s.Store.(RevocationNotifier); ok {
+		return n.SubscribeRevocations(handle)
+	}
+	return func() {}, nil
+}
+
+func (s *tieredStore) status() StoreStatus {
+	if r, ok := s.Store.(statusReporter); ok {
+		return r.status()
+	}
+	return StoreStatus{Backend: "redis"}
+}
+
+func (s *tieredStore) Close() error {
+	if s.stopSub != nil {
+		s.stopSub()
+	}
+	return s.Store.Close()
+}
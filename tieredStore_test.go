@@ -0,0 +1,110 @@
+package gosjwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTieredStore(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 命中本地缓存时不访问Redis，过期后重新读取
+	t.Run("LocalHit", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.Cache.LocalCacheTTL = 50
+		handler := newTestHandler(t, config)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		key := handler.TokenKey(token)
+		assert.False(t, isRevokedForTest(handler, token))
+
+		// 直接写入Redis的撤销记录在本地缓存过期前不可见
+		assert.NoError(t, mr.Set("test_blacklist:"+key, "true"))
+		mr.Del("test_token:" + key)
+		assert.False(t, isRevokedForTest(handler, token))
+		_, found, err := handler.store.GetToken(ctx, key)
+		assert.NoError(t, err)
+		assert.True(t, found)
+
+		time.Sleep(60 * time.Millisecond)
+		assert.True(t, isRevokedForTest(handler, token))
+		_, found, _ = handler.store.GetToken(ctx, key)
+		assert.False(t, found)
+		assert.Greater(t, handler.MemoryStats().Entries, 0)
+	})
+
+	// 测试用例2: 其他实例撤销后通过广播立即清除本地缓存
+	t.Run("CrossInstanceInvalidation", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.Cache.LocalCacheTTL = 60000
+		a := newTestHandler(t, config)
+		b := newTestHandler(t, config)
+
+		token, err := a.ReleaseToken(1)
+		assert.NoError(t, err)
+		_, _, err = a.ParseToken(token)
+		assert.NoError(t, err)
+		_, _, err = b.ParseToken(token)
+		assert.NoError(t, err)
+
+		assert.NoError(t, b.RevokeToken(token))
+		_, _, err = b.ParseToken(token)
+		assert.ErrorIs(t, err, ErrTokenRevoked)
+		assert.Eventually(t, func() bool {
+			_, _, err := a.ParseToken(token)
+			return err == ErrTokenRevoked
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	// 测试用例3: 状态与撤销快照透传到Redis存储
+	t.Run("Passthrough", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.Cache.LocalCacheTTL = 1000
+		handler := newTestHandler(t, config)
+
+		assert.Equal(t, "redis", handler.StoreStatus().Backend)
+		token, _ := handler.ReleaseToken(1)
+		assert.NoError(t, handler.RevokeToken(token))
+		snapshot, err := handler.revocationSnapshot()
+		assert.NoError(t, err)
+		assert.Contains(t, snapshot, handler.TokenKey(token))
+	})
+}
+
+// 对比Redis直查与本地一级缓存下Gin中间件的鉴权耗时
+func BenchmarkTieredMiddleware(b *testing.B) {
+	mr := miniredis.RunT(b)
+
+	run := func(b *testing.B, localTTL int) {
+		config := newRedisTestConfig(mr.Addr())
+		config.Cache.LocalCacheTTL = localTTL
+		handler, err := NewJwtHandler(config)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer handler.Close()
+		r := setupGraceRouter(handler)
+
+		token, err := handler.ReleaseToken(1)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if w := performRequest(r, token); w.Code != 200 {
+				b.Fatalf("鉴权失败: %d", w.Code)
+			}
+		}
+	}
+
+	b.Run("Redis", func(b *testing.B) { run(b, 0) })
+	b.Run("Tiered", func(b *testing.B) { run(b, 1000) })
+}
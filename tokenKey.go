@@ -0,0 +1,125 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/19 16:48:05
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/19 16:48:05
+ * Description: 存储键，存储中不保存原始Token
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tokenKeyContext 由SigningKey派生存储键密钥时使用的上下文，避免与签名用途混用
+const tokenKeyContext = "gos-jwt token key"
+
+// tokenKeySecret 存储键HMAC密钥，未配置时由SigningKey派生
+func tokenKeySecret(config *Config) []byte {
+	if len(config.TokenKeySecret) > 0 {
+		return config.TokenKeySecret
+	}
+	mac := hmac.New(sha256.New, config.SigningKey)
+	mac.Write([]byte(tokenKeyContext))
+	return mac.Sum(nil)
+}
+
+// hashTokenKey 计算Token的HMAC-SHA256，结果不含"."，可与原始Token区分
+func hashTokenKey(secret []byte, tokenString string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(tokenString))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TokenKey 返回Token在存储中的键，缓存、黑名单与宽限期状态均使用该键
+// 读取存储的一方无法据此还原或重放Token
+func (j *JwtHandler) TokenKey(tokenString string) string {
+	return hashTokenKey(j.keySecret, tokenString)
+}
+
+// isRawTokenKey 是否为旧版本以原始Token为键写入的记录
+func isRawTokenKey(key string) bool {
+	return strings.Count(key, ".") == 2
+}
+
+// MigrateTokenKeys 将Redis中以原始Token为键的撤销记录改写为HMAC键并保留剩余过期时间，
+// 同时删除旧格式的Token缓存，返回处理的记录数
+// 滚动升级期间可开启 LegacyTokenKeys，待迁移完成后关闭
+func MigrateTokenKeys(config *Config) (int, error) {
+	if config.Cache.Type != "redis" {
+		return 0, fmt.Errorf("存储键迁移仅支持Redis缓存")
+	}
+	store, err := newRedisStore(config.Cache)
+	if err != nil {
+		return 0, fmt.Errorf("初始化存储失败: %v", err)
+	}
+	defer store.Close()
+
+	secret := tokenKeySecret(config)
+	return store.migrateTokenKeys(func(tokenString string) string {
+		return hashTokenKey(secret, tokenString)
+	})
+}
+
+func (s *redisStore) migrateTokenKeys(keyOf func(tokenString string) string) (int, error) {
+	ctx := context.Background()
+	migrated := 0
+
+	// 撤销记录：按剩余时间改写到新键
+	prefix := s.prefix + "blacklist:"
+	keys, err := s.scanKeys(ctx, prefix+"*")
+	if err != nil {
+		return migrated, err
+	}
+	for _, fullKey := range keys {
+		raw := strings.TrimPrefix(fullKey, prefix)
+		if !isRawTokenKey(raw) {
+			continue
+		}
+		ttl, err := s.client.PTTL(ctx, fullKey).Result()
+		if err != nil {
+			return migrated, fmt.Errorf("读取撤销记录失败: %v", err)
+		}
+		if ttl == -2*time.Nanosecond {
+			continue // 已过期
+		}
+		if ttl < 0 {
+			ttl = 0
+		}
+		value, err := s.client.Get(ctx, fullKey).Bytes()
+		if err != nil {
+			continue
+		}
+		if err := s.client.Set(ctx, prefix+keyOf(raw), value, ttl).Err(); err != nil {
+			return migrated, fmt.Errorf("写入撤销记录失败: %v", err)
+		}
+		if err := s.client.Del(ctx, fullKey).Err(); err != nil {
+			return migrated, fmt.Errorf("删除旧撤销记录失败: %v", err)
+		}
+		migrated++
+	}
+
+	// Token缓存：旧格式无法读取，直接删除
+	prefix = s.prefix + "token:"
+	keys, err = s.scanKeys(ctx, prefix+"*")
+	if err != nil {
+		return migrated, err
+	}
+	for _, fullKey := range keys {
+		if !isRawTokenKey(strings.TrimPrefix(fullKey, prefix)) {
+			continue
+		}
+		if err := s.client.Del(ctx, fullKey).Err(); err != nil {
+			return migrated, fmt.Errorf("删除旧Token缓存失败: %v", err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,106 @@
+package gosjwt
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// assertNoRawToken 检查Redis中的键和字符串值均不包含原始Token
+func assertNoRawToken(t *testing.T, mr *miniredis.Miniredis, tokens ...string) {
+	t.Helper()
+	for _, key := range mr.Keys() {
+		value, _ := mr.Get(key)
+		for _, token := range tokens {
+			assert.NotContains(t, key, token)
+			assert.NotContains(t, value, token)
+		}
+	}
+}
+
+func TestTokenKey(t *testing.T) {
+	ctx := context.Background()
+	// 测试用例1: 签发、续期与撤销后存储中不出现原始Token
+	t.Run("NoRawTokenInStore", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		config.GracePeriod = 5
+		handler := newTestHandler(t, config)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+
+		// 已过期的Token进入宽限期，续期签发新Token
+		config.Expires = -1
+		expired, err := handler.ReleaseToken(2)
+		assert.NoError(t, err)
+		w := performRequest(setupGraceRouter(handler), expired)
+		assert.Equal(t, 200, w.Code)
+		newToken := strings.TrimPrefix(w.Header().Get("Authorization"), "Bearer ")
+		assert.NotEmpty(t, newToken)
+
+		assert.NoError(t, handler.RevokeToken(token))
+		assert.True(t, isRevokedForTest(handler, token))
+		assert.True(t, mr.Exists("test_blacklist:"+handler.TokenKey(token)))
+		assertNoRawToken(t, mr, token, expired, newToken)
+	})
+
+	// 测试用例2: 键由密钥决定，可单独配置
+	t.Run("Secret", func(t *testing.T) {
+		a := newTestHandler(t, &Config{SigningKey: []byte("key-a"), Cache: CacheConfig{Type: "memory"}})
+		b := newTestHandler(t, &Config{SigningKey: []byte("key-a"), TokenKeySecret: []byte("secret"), Cache: CacheConfig{Type: "memory"}})
+
+		assert.Equal(t, a.TokenKey("x.y.z"), a.TokenKey("x.y.z"))
+		assert.NotEqual(t, a.TokenKey("x.y.z"), b.TokenKey("x.y.z"))
+		assert.False(t, isRawTokenKey(a.TokenKey("x.y.z")))
+	})
+
+	// 测试用例3: 开启兼容后识别以原始Token为键的历史撤销记录
+	t.Run("LegacyKeys", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		handler := newTestHandler(t, config)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, mr.Set("test_blacklist:"+token, "true"))
+
+		assert.False(t, isRevokedForTest(handler, token))
+		config.LegacyTokenKeys = true
+		assert.True(t, isRevokedForTest(handler, token))
+	})
+
+	// 测试用例4: 迁移历史记录并保留剩余过期时间
+	t.Run("Migrate", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		config := newRedisTestConfig(mr.Addr())
+		handler := newTestHandler(t, config)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, mr.Set("test_blacklist:"+token, "true"))
+		mr.SetTTL("test_blacklist:"+token, time.Hour)
+		mr.HSet("test_token:"+token, "userId", "1")
+		_, err = handler.store.RaiseEpoch(ctx, 100)
+		assert.NoError(t, err)
+
+		migrated, err := MigrateTokenKeys(config)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, migrated)
+
+		assert.False(t, mr.Exists("test_blacklist:"+token))
+		assert.False(t, mr.Exists("test_token:"+token))
+		assert.Equal(t, time.Hour, mr.TTL("test_blacklist:"+handler.TokenKey(token)))
+		assert.True(t, mr.Exists("test_blacklist:"+revocationEpochKey))
+		assert.True(t, isRevokedForTest(handler, token))
+		assertNoRawToken(t, mr, token)
+
+		// 内存缓存无需迁移
+		_, err = MigrateTokenKeys(&Config{Cache: CacheConfig{Type: "memory"}})
+		assert.Error(t, err)
+	})
+}
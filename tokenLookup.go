@@ -0,0 +1,199 @@
+/**
+ * @Author: guxline zjguoxin@163.com
+ * @Date: 2026/10/20 00:12:36
+ * @LastEditors: guxline zjguoxin@163.com
+ * @LastEditTime: 2026/10/20 00:12:36
+ * Description: 可配置的Token提取：头部、查询参数、Cookie与表单
+ * Copyright: Copyright (©) 2025 中易综服. All rights reserved.
+ */
+package gosjwt
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenLookupType Token所在位置
+type TokenLookupType string
+
+const (
+	LookupHeader TokenLookupType = "header" // 请求头，如Authorization、X-Token
+	LookupQuery  TokenLookupType = "query"  // 查询参数，如WebSocket握手的?token=
+	LookupCookie TokenLookupType = "cookie" // Cookie
+	LookupForm   TokenLookupType = "form"   // 表单字段(application/x-www-form-urlencoded或multipart/form-data)，请求体不超过MaxTokenSize+4KB
+)
+
+// defaultMaxTokenSize 默认Token最大长度(字节)
+const defaultMaxTokenSize = 8192
+
+// formBodyOverhead 表单请求体在Token长度上限之外允许的部分(字节)，容纳其他字段与multipart边界
+const formBodyOverhead = 4096
+
+// TokenLookup 一个Token来源
+type TokenLookup struct {
+	Type   TokenLookupType // 位置
+	Name   string          // 头部、参数、Cookie或表单字段名
+	Scheme string          // 值的认证方案前缀，如"Bearer"，比较时不区分大小写；为空表示整个值即为Token
+}
+
+// defaultTokenLookup 默认只从Authorization头部读取Bearer Token
+var defaultTokenLookup = []TokenLookup{{Type: LookupHeader, Name: "Authorization", Scheme: "Bearer"}}
+
+// describe 用于错误信息的来源描述
+func (l TokenLookup) describe() string {
+	switch l.Type {
+	case LookupQuery:
+		return l.Name + " query parameter"
+	case LookupCookie:
+		return l.Name + " cookie"
+	case LookupForm:
+		return l.Name + " form field"
+	default:
+		return l.Name + " header"
+	}
+}
+
+// tokenRequest 各框架请求的Token读取接口
+type tokenRequest interface {
+	tokenValue(typ TokenLookupType, name string) string
+	requestMethod() string
+}
+
+// httpTokenRequest 基于net/http请求读取，Gin、Echo与HttpMiddleware共用
+type httpTokenRequest struct {
+	r       *http.Request
+	maxForm int64 // 表单来源允许解析的请求体上限
+}
+
+func (h httpTokenRequest) tokenValue(typ TokenLookupType, name string) string {
+	switch typ {
+	case LookupHeader:
+		return h.r.Header.Get(name)
+	case LookupQuery:
+		return h.r.URL.Query().Get(name)
+	case LookupCookie:
+		if c, err := h.r.Cookie(name); err == nil {
+			return c.Value
+		}
+	case LookupForm:
+		// 只读取请求体中的字段，查询参数需配置LookupQuery
+		// 未声明长度或超过上限的请求体不解析，避免认证前读取大请求体；解析后请求体已被读取
+		if h.r.ContentLength < 0 || h.r.ContentLength > h.maxForm {
+			return ""
+		}
+		h.r.Body = http.MaxBytesReader(nil, h.r.Body, h.maxForm)
+		return h.r.PostFormValue(name)
+	}
+	return ""
+}
+
+func (h httpTokenRequest) requestMethod() string {
+	return h.r.Method
+}
+
+// validateTokenLookup 检查Token来源配置
+func validateTokenLookup(lookups []TokenLookup) error {
+	for i, l := range lookups {
+		switch l.Type {
+		case LookupHeader, LookupQuery, LookupCookie, LookupForm:
+		default:
+			return fmt.Errorf("Token来源配置无效: 第%d项的类型%q不受支持", i+1, l.Type)
+		}
+		if l.Name == "" {
+			return fmt.Errorf("Token来源配置无效: 第%d项缺少名称", i+1)
+		}
+	}
+	return nil
+}
+
+// tokenLookups 配置的Token来源，未配置时使用默认值；Cookie模式下追加Token Cookie
+func (j *JwtHandler) tokenLookups() []TokenLookup {
+	lookups := defaultTokenLookup
+	if len(j.Config.TokenLookup) > 0 {
+		lookups = j.Config.TokenLookup
+	}
+	if !j.Config.Cookie.Enabled {
+		return lookups
+	}
+	cookie := TokenLookup{Type: LookupCookie, Name: j.Config.Cookie.name()}
+	for _, l := range lookups {
+		if l == cookie {
+			return lookups
+		}
+	}
+	return append(lookups[:len(lookups):len(lookups)], cookie)
+}
+
+// maxTokenSize Token最大长度
+func (j *JwtHandler) maxTokenSize() int {
+	if j.Config.MaxTokenSize > 0 {
+		return j.Config.MaxTokenSize
+	}
+	return defaultMaxTokenSize
+}
+
+// maxFormSize 表单来源允许解析的请求体上限
+func (j *JwtHandler) maxFormSize() int64 {
+	return int64(j.maxTokenSize() + formBodyOverhead)
+}
+
+// extractToken 按配置顺序读取所有来源，返回Token及最先携带它的来源：任一来源格式错误或超长时拒绝；
+// 多个来源都携带Token时必须一致，避免不同组件按不同来源识别出不同用户
+func (j *JwtHandler) extractToken(req tokenRequest) (string, TokenLookup, authResult) {
+	lookups := j.tokenLookups()
+	maxSize := j.maxTokenSize()
+
+	var token string
+	var from TokenLookup
+	for _, l := range lookups {
+		value := req.tokenValue(l.Type, l.Name)
+		if value == "" {
+			continue
+		}
+		t, ok := parseTokenScheme(value, l.Scheme)
+		if !ok {
+			return "", l, authFailure(http.StatusUnauthorized, invalidFormatMessage(l))
+		}
+		if len(t) > maxSize {
+			return "", l, authFailure(http.StatusUnauthorized, "Token too large")
+		}
+		if token == "" {
+			token, from = t, l
+			continue
+		}
+		if t != token {
+			return "", l, authFailure(http.StatusUnauthorized,
+				fmt.Sprintf("Conflicting tokens in %s and %s", from.describe(), l.describe()))
+		}
+	}
+
+	if token == "" {
+		if len(lookups) == 1 {
+			return "", from, authFailure(http.StatusUnauthorized, lookups[0].describe()+" required")
+		}
+		return "", from, authFailure(http.StatusUnauthorized, "Token required")
+	}
+	return token, from, authResult{}
+}
+
+// parseTokenScheme 去除认证方案前缀，方案名不区分大小写，与Token之间允许多个空白
+func parseTokenScheme(value, scheme string) (string, bool) {
+	if scheme != "" {
+		n := len(scheme)
+		if len(value) <= n || !strings.EqualFold(value[:n], scheme) || (value[n] != ' ' && value[n] != '\t') {
+			return "", false
+		}
+		value = value[n:]
+	}
+	token := strings.TrimSpace(value)
+	return token, token != ""
+}
+
+// invalidFormatMessage 格式错误的提示，Authorization头部沿用原有信息
+func invalidFormatMessage(l TokenLookup) string {
+	if l.Type == LookupHeader && strings.EqualFold(l.Name, "Authorization") {
+		return "Invalid authorization format"
+	}
+	return "Invalid " + l.describe() + " format"
+}
@@ -0,0 +1,194 @@
+package gosjwt
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenLookup(t *testing.T) {
+	allSources := []TokenLookup{
+		{Type: LookupHeader, Name: "Authorization", Scheme: "Bearer"},
+		{Type: LookupHeader, Name: "X-Token"},
+		{Type: LookupCookie, Name: "jwt"},
+		{Type: LookupQuery, Name: "token"},
+		{Type: LookupForm, Name: "access_token"},
+	}
+
+	// 在所有框架上执行同一请求，检查状态码与响应体
+	check := func(t *testing.T, config func() *Config, build func(t *testing.T, token string) *http.Request, status int, body string) {
+		for _, router := range routers {
+			c := config()
+			c.Expires = 3600
+			h := newTestHandler(t, c)
+			token, err := h.ReleaseToken(7)
+			assert.NoError(t, err)
+			w := httptest.NewRecorder()
+			router.setup(h).ServeHTTP(w, build(t, token))
+			assert.Equal(t, status, w.Code, router.name)
+			assert.JSONEq(t, body, w.Body.String(), router.name)
+		}
+	}
+	withHeader := func(name, prefix string) func(t *testing.T, token string) *http.Request {
+		return func(t *testing.T, token string) *http.Request {
+			req := httptest.NewRequest("GET", "/grace", nil)
+			req.Header.Set(name, prefix+token)
+			return req
+		}
+	}
+	defaults := func() *Config { return &Config{} }
+	all := func() *Config { return &Config{TokenLookup: allSources} }
+
+	// 测试用例1: 认证方案不区分大小写，方案与Token之间允许多个空白
+	t.Run("CaseInsensitiveScheme", func(t *testing.T) {
+		check(t, defaults, withHeader("Authorization", "bearer "), http.StatusOK, `{"userID":7}`)
+		check(t, defaults, withHeader("Authorization", "BEARER \t "), http.StatusOK, `{"userID":7}`)
+		check(t, defaults, withHeader("Authorization", "Bearer"), http.StatusUnauthorized, `{"error":"Invalid authorization format"}`)
+		check(t, defaults, withHeader("Authorization", "Bearerx "), http.StatusUnauthorized, `{"error":"Invalid authorization format"}`)
+		check(t, defaults, withHeader("Authorization", "Basic "), http.StatusUnauthorized, `{"error":"Invalid authorization format"}`)
+	})
+
+	// 测试用例2: 各来源分别携带Token均可通过认证
+	t.Run("Sources", func(t *testing.T) {
+		sources := map[string]func(t *testing.T, token string) *http.Request{
+			"Header": withHeader("X-Token", ""),
+			"Cookie": func(t *testing.T, token string) *http.Request {
+				req := httptest.NewRequest("GET", "/grace", nil)
+				req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+				return req
+			},
+			"Query": func(t *testing.T, token string) *http.Request {
+				return httptest.NewRequest("GET", "/grace?token="+url.QueryEscape(token), nil)
+			},
+			"Form": func(t *testing.T, token string) *http.Request {
+				form := url.Values{"access_token": {token}}.Encode()
+				req := httptest.NewRequest("POST", "/grace", strings.NewReader(form))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			},
+		}
+		for name, build := range sources {
+			t.Run(name, func(t *testing.T) {
+				check(t, all, build, http.StatusOK, `{"userID":7}`)
+			})
+		}
+	})
+
+	// 测试用例3: 多个来源携带相同Token时通过，不一致时拒绝
+	t.Run("Conflict", func(t *testing.T) {
+		check(t, all, func(t *testing.T, token string) *http.Request {
+			req := withHeader("Authorization", "Bearer ")(t, token)
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+			return req
+		}, http.StatusOK, `{"userID":7}`)
+
+		check(t, all, func(t *testing.T, token string) *http.Request {
+			req := withHeader("Authorization", "Bearer ")(t, token)
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: "other.token.value"})
+			return req
+		}, http.StatusUnauthorized, `{"error":"Conflicting tokens in Authorization header and jwt cookie"}`)
+
+		// 次要来源格式错误同样拒绝
+		check(t, func() *Config {
+			return &Config{TokenLookup: []TokenLookup{
+				{Type: LookupCookie, Name: "jwt"},
+				{Type: LookupHeader, Name: "X-Token", Scheme: "Token"},
+			}}
+		}, func(t *testing.T, token string) *http.Request {
+			req := withHeader("X-Token", "Bearer ")(t, token)
+			req.AddCookie(&http.Cookie{Name: "jwt", Value: token})
+			return req
+		}, http.StatusUnauthorized, `{"error":"Invalid X-Token header format"}`)
+	})
+
+	// 测试用例4: 超过长度上限的Token在访问存储前被拒绝
+	t.Run("MaxTokenSize", func(t *testing.T) {
+		check(t, func() *Config { return &Config{MaxTokenSize: 16} },
+			withHeader("Authorization", "Bearer "), http.StatusUnauthorized, `{"error":"Token too large"}`)
+		check(t, defaults, func(t *testing.T, token string) *http.Request {
+			return withHeader("Authorization", "Bearer ")(t, strings.Repeat("a", defaultMaxTokenSize+1))
+		}, http.StatusUnauthorized, `{"error":"Token too large"}`)
+	})
+
+	// 测试用例5: 缺少Token时的提示与来源对应
+	t.Run("Missing", func(t *testing.T) {
+		none := func(t *testing.T, token string) *http.Request { return httptest.NewRequest("GET", "/grace", nil) }
+		check(t, defaults, none, http.StatusUnauthorized, `{"error":"Authorization header required"}`)
+		check(t, func() *Config {
+			return &Config{TokenLookup: []TokenLookup{{Type: LookupCookie, Name: "jwt"}}}
+		}, none, http.StatusUnauthorized, `{"error":"jwt cookie required"}`)
+		check(t, all, none, http.StatusUnauthorized, `{"error":"Token required"}`)
+	})
+
+	// 测试用例6: 无效的来源配置在创建处理器时报错
+	t.Run("InvalidConfig", func(t *testing.T) {
+		for _, lookups := range [][]TokenLookup{
+			{{Type: "body", Name: "token"}},
+			{{Type: LookupHeader}},
+		} {
+			_, err := NewJwtHandler(&Config{SigningKey: []byte("k"), Cache: CacheConfig{Type: "memory"}, TokenLookup: lookups})
+			assert.Error(t, err)
+		}
+	})
+
+	// 测试用例7: gRPC按header来源读取metadata
+	t.Run("Grpc", func(t *testing.T) {
+		h := newTestHandler(t, &Config{Expires: 3600, TokenLookup: []TokenLookup{
+			{Type: LookupHeader, Name: "Authorization", Scheme: "Bearer"},
+			{Type: LookupHeader, Name: "X-Token"},
+		}})
+		client := newGrpcTestClient(t, h)
+		token, err := h.ReleaseToken(7)
+		assert.NoError(t, err)
+		req := &grpc_health_v1.HealthCheckRequest{}
+
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-token", token)
+		_, err = client.Check(ctx, req)
+		assert.NoError(t, err)
+
+		ctx = metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer "+token, "x-token", "other")
+		_, err = client.Check(ctx, req)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.Equal(t, "Conflicting tokens in Authorization header and X-Token header", status.Convert(err).Message())
+	})
+
+	// 测试用例8: 超过上限或未声明长度的表单请求体不解析
+	t.Run("FormBodyLimit", func(t *testing.T) {
+		formOnly := func() *Config {
+			return &Config{MaxTokenSize: 1024, TokenLookup: []TokenLookup{{Type: LookupForm, Name: "access_token"}}}
+		}
+		withForm := func(padding int) func(t *testing.T, token string) *http.Request {
+			return func(t *testing.T, token string) *http.Request {
+				form := url.Values{"access_token": {token}, "note": {strings.Repeat("a", padding)}}.Encode()
+				req := httptest.NewRequest("POST", "/grace", strings.NewReader(form))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				return req
+			}
+		}
+		check(t, formOnly, withForm(1024), http.StatusOK, `{"userID":7}`)
+		check(t, formOnly, withForm(1024+formBodyOverhead), http.StatusUnauthorized, `{"error":"access_token form field required"}`)
+
+		// 分块传输的请求体长度未知，net/http中间件不读取
+		h := newTestHandler(t, &Config{Expires: 3600, TokenLookup: formOnly().TokenLookup})
+		token, err := h.ReleaseToken(7)
+		assert.NoError(t, err)
+		req := withForm(0)(t, token)
+		req.ContentLength = -1
+		w := httptest.NewRecorder()
+		setupHttpRouter(h).ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		body, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Contains(t, string(body), "access_token=")
+	})
+}
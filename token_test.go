@@ -1,7 +1,9 @@
 package gosjwt
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -91,3 +93,123 @@ func TestTokenReleaseAndParse(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+// ttlRecorder 记录黑名单写入时长的存储包装
+type ttlRecorder struct {
+	Store
+	ttls map[string]time.Duration
+}
+
+func (r *ttlRecorder) Revoke(ctx context.Context, key string, ttl time.Duration) error {
+	r.ttls[key] = ttl
+	return r.Store.Revoke(ctx, key, ttl)
+}
+
+// recordTTLs 包装处理器的存储以记录黑名单写入时长
+func recordTTLs(handler *JwtHandler) *ttlRecorder {
+	recorder := &ttlRecorder{Store: handler.store, ttls: make(map[string]time.Duration)}
+	handler.store = recorder
+	return recorder
+}
+
+func TestRevocationTTL(t *testing.T) {
+	// 测试用例1: 长期Token的撤销记录保留至自然过期之后
+	t.Run("LongLivedToken", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: 7200, GracePeriod: 300, Leeway: 30})
+		recorder := recordTTLs(handler)
+
+		token, err := handler.ReleaseToken(1)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+
+		expected := 7200*time.Second + 300*time.Second + 30*time.Second
+		assert.InDelta(t, expected.Seconds(), recorder.ttls[handler.TokenKey(token)].Seconds(), 2)
+
+		_, _, err = handler.ParseToken(token)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "token已被撤销")
+	})
+
+	// 测试用例2: 已过期Token至少保留最短时长
+	t.Run("ExpiredToken", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: -10})
+		recorder := recordTTLs(handler)
+
+		token, err := handler.ReleaseToken(2)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		assert.Equal(t, minRevocationTTL, recorder.ttls[handler.TokenKey(token)])
+	})
+
+	// 测试用例3: 自定义保留策略
+	t.Run("CustomPolicy", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{
+			Expires: 60,
+			RevocationTTLFunc: func(claims *Claims) time.Duration {
+				return 48 * time.Hour
+			},
+		})
+		recorder := recordTTLs(handler)
+
+		token, err := handler.ReleaseToken(3)
+		assert.NoError(t, err)
+		assert.NoError(t, handler.RevokeToken(token))
+		assert.Equal(t, 48*time.Hour, recorder.ttls[handler.TokenKey(token)])
+	})
+
+	// 测试用例4: 超过宽限窗口的过期Token不会重新进入宽限期
+	t.Run("OutsideGraceWindow", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: -120, GracePeriod: 60})
+
+		token, err := handler.ReleaseToken(4)
+		assert.NoError(t, err)
+
+		w := performRequest(setupGraceRouter(handler), token)
+		assert.Equal(t, 401, w.Code)
+		assert.Contains(t, w.Body.String(), "Token expired")
+		assert.Empty(t, w.Header().Get("Authorization"))
+	})
+
+	// 测试用例5: 时钟偏差内刚过期的Token仍然有效；超过过期时间+偏差+宽限期后被拒绝
+	t.Run("Leeway", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: -2, GracePeriod: 5, Leeway: 10})
+
+		token, err := handler.ReleaseToken(5)
+		assert.NoError(t, err)
+		_, claims, err := handler.ParseToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(5), claims.UserId)
+		w := performRequest(setupGraceRouter(handler), token)
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get("Authorization"), "偏差内不进入宽限期")
+
+		expired := newTestHandler(t, &Config{Expires: -20, GracePeriod: 5, Leeway: 10})
+		token, err = expired.ReleaseToken(6)
+		assert.NoError(t, err)
+		_, _, err = expired.ParseToken(token)
+		assert.True(t, isExpiredError(err))
+		w = performRequest(setupGraceRouter(expired), token)
+		assert.Equal(t, 401, w.Code)
+		assert.Contains(t, w.Body.String(), "Token expired")
+		assert.Empty(t, w.Header().Get("Authorization"))
+	})
+
+	// 测试用例6: 临近宽限窗口末尾续期时，旧Token在原截止时间后即被拒绝
+	t.Run("LateRenewalDeadline", func(t *testing.T) {
+		handler := newTestHandler(t, &Config{Expires: -3, GracePeriod: 4})
+		r := setupGraceRouter(handler)
+
+		token, err := handler.ReleaseToken(7)
+		assert.NoError(t, err)
+		w := performRequest(r, token)
+		assert.Equal(t, 200, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Authorization"))
+
+		// 原截止时间为过期时间+宽限期，距今不超过1秒；续期时间+宽限期仍在3秒之后
+		// 截止后由调度器转入黑名单或在请求时判定宽限期结束
+		time.Sleep(2 * time.Second)
+		w = performRequest(r, token)
+		assert.Equal(t, 401, w.Code)
+		assert.Empty(t, w.Header().Get("Authorization"))
+	})
+}